@@ -0,0 +1,202 @@
+package zfs
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DelegationTarget is the kind of entity permissions are delegated to
+type DelegationTarget string
+
+// Delegation targets
+const (
+	DelegateToUser       DelegationTarget = "user"
+	DelegateToGroup      DelegationTarget = "group"
+	DelegateToEveryone   DelegationTarget = "everyone"
+	DelegateToCreateTime DelegationTarget = "create"
+	DelegateToSet        DelegationTarget = "set"
+)
+
+// Delegation describes permissions delegated to unprivileged users
+type Delegation struct {
+	// Target is the kind of entity permissions are delegated to.
+	// DelegateToCreateTime grants permissions to the creator of descendent dataset,
+	// DelegateToSet defines a permission set.
+	Target DelegationTarget
+
+	// Names are the names of users or groups, for DelegateToSet it contains the name of the set prefixed by @
+	Names []string
+
+	// Permissions are the names of permissions and permission sets prefixed by @
+	Permissions []string
+
+	// Local and Descendent limit the scope of permissions to the dataset or its descendents,
+	// permissions apply to both if none is set, they are ignored for create time permissions and sets
+	Local      bool
+	Descendent bool
+}
+
+func (d Delegation) args(cmd string) ([]string, error) {
+	if len(d.Permissions) == 0 && cmd == "allow" {
+		return nil, errors.New("no permissions specified")
+	}
+
+	args := []string{cmd}
+	if d.Target != DelegateToCreateTime && d.Target != DelegateToSet {
+		if d.Local {
+			args = append(args, "-l")
+		}
+		if d.Descendent {
+			args = append(args, "-d")
+		}
+	}
+
+	switch d.Target {
+	case DelegateToUser, DelegateToGroup:
+		if len(d.Names) == 0 {
+			return nil, errors.Errorf("no names specified for %s", d.Target)
+		}
+		args = append(args, "-"+string(d.Target[0]), strings.Join(d.Names, ","))
+	case DelegateToEveryone:
+		args = append(args, "-e")
+	case DelegateToCreateTime:
+		args = append(args, "-c")
+	case DelegateToSet:
+		if len(d.Names) != 1 || !strings.HasPrefix(d.Names[0], "@") {
+			return nil, errors.New("exactly one set name prefixed by @ must be specified")
+		}
+		args = append(args, "-s", d.Names[0])
+	default:
+		return nil, errors.Errorf("unknown delegation target %q", d.Target)
+	}
+
+	if len(d.Permissions) > 0 {
+		args = append(args, strings.Join(d.Permissions, ","))
+	}
+	return args, nil
+}
+
+// Permission is a set of permissions delegated to a single entity
+type Permission struct {
+	Target      DelegationTarget
+	Name        string
+	Permissions []string
+}
+
+// DatasetPermissions are permissions delegated on a dataset
+type DatasetPermissions struct {
+	Dataset string
+
+	// Sets maps names of permission sets to their permissions
+	Sets map[string][]string
+
+	CreateTime      []string
+	Local           []Permission
+	Descendent      []Permission
+	LocalDescendent []Permission
+}
+
+// Allow delegates permissions on the filesystem
+func (d *Filesystem) Allow(ctx context.Context, delegation Delegation) error {
+	args, err := delegation.args("allow")
+	if err != nil {
+		return err
+	}
+	_, err = zfs(ctx, append(args, d.Info.Name)...)
+	return err
+}
+
+// Unallow revokes permissions delegated on the filesystem.
+// If delegation contains no permissions, all the permissions of the target are revoked.
+// If recursive is set, permissions are revoked from descendents too.
+func (d *Filesystem) Unallow(ctx context.Context, delegation Delegation, recursive bool) error {
+	args, err := delegation.args("unallow")
+	if err != nil {
+		return err
+	}
+	if recursive {
+		args = append([]string{args[0], "-r"}, args[1:]...)
+	}
+	_, err = zfs(ctx, append(args, d.Info.Name)...)
+	return err
+}
+
+// Permissions returns permissions delegated on the filesystem, including the ones inherited from its ancestors.
+// The filesystem itself comes first followed by its ancestors.
+func (d *Filesystem) Permissions(ctx context.Context) ([]DatasetPermissions, error) {
+	out, err := zfs(ctx, "allow", d.Info.Name)
+	if err != nil {
+		return nil, err
+	}
+	return parseAllow(out)
+}
+
+func parseAllow(out [][]string) ([]DatasetPermissions, error) {
+	var (
+		result  []DatasetPermissions
+		current *DatasetPermissions
+		section string
+	)
+	for _, line := range out {
+		text := strings.Join(line, "\t")
+		switch {
+		case strings.HasPrefix(text, "---- Permissions on "):
+			name := strings.TrimPrefix(text, "---- Permissions on ")
+			name = strings.TrimSpace(strings.TrimRight(name, "-"))
+			result = append(result, DatasetPermissions{Dataset: name, Sets: map[string][]string{}})
+			current = &result[len(result)-1]
+			section = ""
+		case strings.TrimSpace(text) == "":
+		case text[0] != '\t' && text[0] != ' ':
+			section = strings.TrimSuffix(strings.TrimSpace(text), ":")
+		default:
+			if current == nil {
+				return nil, errors.Errorf("unexpected allow output line: %q", text)
+			}
+			if err := parseAllowEntry(current, section, strings.Fields(text)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+func parseAllowEntry(current *DatasetPermissions, section string, fields []string) error {
+	var permissions *[]Permission
+	switch section {
+	case "Permission sets":
+		if len(fields) != 2 {
+			return errors.Errorf("invalid permission set: %q", strings.Join(fields, " "))
+		}
+		current.Sets[fields[0]] = strings.Split(fields[1], ",")
+		return nil
+	case "Create time permissions":
+		if len(fields) != 1 {
+			return errors.Errorf("invalid create time permissions: %q", strings.Join(fields, " "))
+		}
+		current.CreateTime = strings.Split(fields[0], ",")
+		return nil
+	case "Local permissions":
+		permissions = &current.Local
+	case "Descendent permissions":
+		permissions = &current.Descendent
+	case "Local+Descendent permissions":
+		permissions = &current.LocalDescendent
+	default:
+		return errors.Errorf("unknown permission section %q", section)
+	}
+
+	var p Permission
+	switch {
+	case len(fields) == 2 && fields[0] == string(DelegateToEveryone):
+		p = Permission{Target: DelegateToEveryone, Permissions: strings.Split(fields[1], ",")}
+	case len(fields) == 3 && (fields[0] == string(DelegateToUser) || fields[0] == string(DelegateToGroup)):
+		p = Permission{Target: DelegationTarget(fields[0]), Name: fields[1], Permissions: strings.Split(fields[2], ",")}
+	default:
+		return errors.Errorf("invalid permission: %q", strings.Join(fields, " "))
+	}
+	*permissions = append(*permissions, p)
+	return nil
+}
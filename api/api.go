@@ -0,0 +1,801 @@
+// Package api exposes ZFS management over HTTP/JSON API.
+//
+// Dataset names are passed as single URL-escaped path segments, e.g. /filesystems/tank%2Fdata.
+// Supported endpoints:
+//
+//	GET    /filesystems
+//	POST   /filesystems
+//	GET    /filesystems/{filesystem}
+//	DELETE /filesystems/{filesystem}[?recursive=true]
+//	GET    /filesystems/{filesystem}/properties/{property}
+//	PUT    /filesystems/{filesystem}/properties/{property}
+//	GET    /filesystems/{filesystem}/snapshots
+//	POST   /filesystems/{filesystem}/snapshots
+//	GET    /snapshots
+//	GET    /snapshots/{snapshot}
+//	DELETE /snapshots/{snapshot}
+//	POST   /snapshots/{snapshot}/rollback
+//	POST   /snapshots/{snapshot}/clone
+//	GET    /snapshots/{snapshot}/holds
+//	POST   /snapshots/{snapshot}/holds
+//	DELETE /snapshots/{snapshot}/holds/{tag}
+//	GET    /snapshots/{snapshot}/send[?from={snapshot}&raw=true&props=true]
+//	POST   /snapshots/{snapshot}/receive
+//	GET    /pools
+//	GET    /pools/{pool}
+//	GET    /pools/{pool}/properties
+//
+// Rollback destroys newer snapshots so it requires both rollback and destroy actions to be permitted.
+// Properties affecting the host, like mountpoint, can't be set by clients and they are not received.
+// Received filesystems are not mounted.
+// Replication streams produced by zfs send -R are rejected.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/outofforest/go-zfs/v3"
+	"github.com/outofforest/logger"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Config is the configuration of API server
+type Config struct {
+	// Authenticate authenticates requests, all the requests are sent by anonymous principal "" if nil
+	Authenticate AuthenticateFunc
+
+	// Rules grant permissions to principals, request is rejected if no rule permits it
+	Rules []Rule
+}
+
+// Server serves the API
+type Server struct {
+	ctx    context.Context
+	config Config
+}
+
+// New returns new API server. Logger and command hooks stored in ctx are used while handling requests.
+func New(ctx context.Context, config Config) (*Server, error) {
+	if err := validateRules(config.Rules); err != nil {
+		return nil, err
+	}
+	return &Server{ctx: ctx, config: config}, nil
+}
+
+// CreateFilesystemRequest is the body of filesystem creation request
+type CreateFilesystemRequest struct {
+	Name       string            `json:"name"`
+	Properties map[string]string `json:"properties,omitempty"`
+	Parents    bool              `json:"parents,omitempty"`
+	NoMount    bool              `json:"noMount,omitempty"`
+}
+
+// CreateSnapshotRequest is the body of snapshot creation request
+type CreateSnapshotRequest struct {
+	Name string `json:"name"`
+}
+
+// CloneRequest is the body of clone request
+type CloneRequest struct {
+	Name       string            `json:"name"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+// HoldRequest is the body of hold request
+type HoldRequest struct {
+	Tag string `json:"tag"`
+}
+
+// Property is the value of dataset property
+type Property struct {
+	Value  string `json:"value"`
+	Exists bool   `json:"exists"`
+}
+
+// Error is the body of response sent if request fails
+type Error struct {
+	Error string `json:"error"`
+}
+
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e httpError) Error() string {
+	return e.err.Error()
+}
+
+func newError(status int, format string, args ...interface{}) error {
+	return httpError{status: status, err: errors.Errorf(format, args...)}
+}
+
+type request struct {
+	*http.Request
+
+	ctx       context.Context
+	principal string
+	segments  []string
+}
+
+// ServeHTTP handles the request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := valuesContext{Context: r.Context(), values: s.ctx}
+	if err := s.serve(ctx, w, r); err != nil {
+		status, message := errorResponse(err)
+		if status >= http.StatusInternalServerError {
+			logger.Get(ctx).Error("Request failed", zap.String("method", r.Method),
+				zap.String("path", r.URL.Path), zap.Error(err))
+		}
+		writeJSON(w, status, Error{Error: message})
+	}
+}
+
+// errorResponse returns status and message sent to the client.
+// Details of zfs failures, like commands and their output, are never sent.
+func errorResponse(err error) (int, string) {
+	var hErr httpError
+	switch {
+	case errors.As(err, &hErr):
+		return hErr.status, hErr.Error()
+	case zfs.IsNotExist(err):
+		return http.StatusNotFound, "not found"
+	default:
+		return http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) serve(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	segments := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	for i, segment := range segments {
+		var err error
+		if segments[i], err = url.PathUnescape(segment); err != nil {
+			return newError(http.StatusBadRequest, "invalid path segment %q", segment)
+		}
+		if i > 0 {
+			// segments following the collection are names of datasets, properties or tags passed to zfs
+			if err := validateName("path segment", segments[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	var principal string
+	if s.config.Authenticate != nil {
+		var err error
+		if principal, err = s.config.Authenticate(r); err != nil {
+			// reason of the failure might reveal details of the authentication, it is logged only
+			logger.Get(ctx).Warn("Authentication failed", zap.String("method", r.Method),
+				zap.String("path", r.URL.Path), zap.Error(err))
+			return newError(http.StatusUnauthorized, "unauthorized")
+		}
+	}
+
+	req := &request{Request: r, ctx: ctx, principal: principal, segments: segments}
+	switch segments[0] {
+	case "filesystems":
+		return s.serveFilesystems(w, req)
+	case "snapshots":
+		return s.serveSnapshots(w, req)
+	case "pools":
+		return s.servePools(w, req)
+	default:
+		return newError(http.StatusNotFound, "unknown path %q", r.URL.Path)
+	}
+}
+
+func (s *Server) serveFilesystems(w http.ResponseWriter, r *request) error {
+	switch {
+	case len(r.segments) == 1 && r.Method == http.MethodGet:
+		filesystems, err := zfs.Filesystems(r.ctx)
+		if err != nil {
+			return err
+		}
+		infos := []zfs.Info{}
+		for _, fs := range filesystems {
+			if s.allowed(r.principal, fs.Info.Name, ActionRead) {
+				infos = append(infos, fs.Info)
+			}
+		}
+		writeJSON(w, http.StatusOK, infos)
+		return nil
+	case len(r.segments) == 1 && r.Method == http.MethodPost:
+		var body CreateFilesystemRequest
+		if err := decodeJSON(r, &body); err != nil {
+			return err
+		}
+		if err := validateName("filesystem", body.Name); err != nil {
+			return err
+		}
+		if err := validateProperties(body.Properties); err != nil {
+			return err
+		}
+		if err := s.authorize(r.principal, body.Name, ActionCreate); err != nil {
+			return err
+		}
+		if body.Parents {
+			if err := s.authorizeMissingAncestors(r, body.Name); err != nil {
+				return err
+			}
+		}
+		fs, err := zfs.CreateFilesystem(r.ctx, body.Name, zfs.CreateFilesystemOptions{
+			Properties: body.Properties,
+			Parents:    body.Parents,
+			NoMount:    body.NoMount,
+		})
+		if err != nil {
+			return err
+		}
+		writeJSON(w, http.StatusCreated, fs.Info)
+		return nil
+	case len(r.segments) == 1:
+		return methodNotAllowed(r)
+	}
+
+	name := r.segments[1]
+	switch {
+	case len(r.segments) == 2 && r.Method == http.MethodGet:
+		if err := s.authorize(r.principal, name, ActionRead); err != nil {
+			return err
+		}
+		fs, err := zfs.GetFilesystem(r.ctx, name)
+		if err != nil {
+			return err
+		}
+		writeJSON(w, http.StatusOK, fs.Info)
+		return nil
+	case len(r.segments) == 2 && r.Method == http.MethodDelete:
+		if err := s.authorize(r.principal, name, ActionDestroy); err != nil {
+			return err
+		}
+		flags := zfs.DestroyDefault
+		if r.URL.Query().Get("recursive") == "true" {
+			if err := s.authorizeDescendants(r, name, ActionDestroy); err != nil {
+				return err
+			}
+			flags = zfs.DestroyRecursive
+		}
+		fs, err := zfs.GetFilesystem(r.ctx, name)
+		if err != nil {
+			return err
+		}
+		if err := fs.Destroy(r.ctx, flags); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	case len(r.segments) == 2:
+		return methodNotAllowed(r)
+	case len(r.segments) == 4 && r.segments[2] == "properties":
+		return s.serveProperty(w, r, name, r.segments[3])
+	case len(r.segments) == 3 && r.segments[2] == "snapshots":
+		return s.serveFilesystemSnapshots(w, r, name)
+	default:
+		return newError(http.StatusNotFound, "unknown path %q", r.URL.Path)
+	}
+}
+
+func (s *Server) serveProperty(w http.ResponseWriter, r *request, name, key string) error {
+	switch r.Method {
+	case http.MethodGet:
+		if err := s.authorize(r.principal, name, ActionRead); err != nil {
+			return err
+		}
+		fs, err := zfs.GetFilesystem(r.ctx, name)
+		if err != nil {
+			return err
+		}
+		value, exists, err := fs.GetProperty(r.ctx, key)
+		if err != nil {
+			return err
+		}
+		writeJSON(w, http.StatusOK, Property{Value: value, Exists: exists})
+		return nil
+	case http.MethodPut:
+		if err := validateProperty(key); err != nil {
+			return err
+		}
+		if err := s.authorize(r.principal, name, ActionProperty); err != nil {
+			return err
+		}
+		var body Property
+		if err := decodeJSON(r, &body); err != nil {
+			return err
+		}
+		fs, err := zfs.GetFilesystem(r.ctx, name)
+		if err != nil {
+			return err
+		}
+		if err := fs.SetProperty(r.ctx, key, body.Value); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	default:
+		return methodNotAllowed(r)
+	}
+}
+
+func (s *Server) serveFilesystemSnapshots(w http.ResponseWriter, r *request, name string) error {
+	switch r.Method {
+	case http.MethodGet:
+		if err := s.authorize(r.principal, name, ActionRead); err != nil {
+			return err
+		}
+		fs, err := zfs.GetFilesystem(r.ctx, name)
+		if err != nil {
+			return err
+		}
+		snapshots, err := fs.Snapshots(r.ctx)
+		if err != nil {
+			return err
+		}
+		writeJSON(w, http.StatusOK, snapshotInfos(snapshots))
+		return nil
+	case http.MethodPost:
+		if err := s.authorize(r.principal, name, ActionSnapshot); err != nil {
+			return err
+		}
+		var body CreateSnapshotRequest
+		if err := decodeJSON(r, &body); err != nil {
+			return err
+		}
+		if err := validateName("snapshot", body.Name); err != nil {
+			return err
+		}
+		fs, err := zfs.GetFilesystem(r.ctx, name)
+		if err != nil {
+			return err
+		}
+		snapshot, err := fs.Snapshot(r.ctx, body.Name)
+		if err != nil {
+			return err
+		}
+		writeJSON(w, http.StatusCreated, snapshot.Info)
+		return nil
+	default:
+		return methodNotAllowed(r)
+	}
+}
+
+func (s *Server) serveSnapshots(w http.ResponseWriter, r *request) error {
+	if len(r.segments) == 1 {
+		if r.Method != http.MethodGet {
+			return methodNotAllowed(r)
+		}
+		snapshots, err := zfs.Snapshots(r.ctx)
+		if err != nil {
+			return err
+		}
+		allowed := make([]*zfs.Snapshot, 0, len(snapshots))
+		for _, snapshot := range snapshots {
+			if s.allowed(r.principal, datasetOf(snapshot.Info.Name), ActionRead) {
+				allowed = append(allowed, snapshot)
+			}
+		}
+		writeJSON(w, http.StatusOK, snapshotInfos(allowed))
+		return nil
+	}
+
+	name := r.segments[1]
+	dataset := datasetOf(name)
+	if dataset == name {
+		return newError(http.StatusBadRequest, "%q is not a snapshot name", name)
+	}
+
+	var action string
+	if len(r.segments) > 2 {
+		action = r.segments[2]
+	}
+	switch {
+	case len(r.segments) == 2 && r.Method == http.MethodGet:
+		if err := s.authorize(r.principal, dataset, ActionRead); err != nil {
+			return err
+		}
+		snapshot, err := zfs.GetSnapshot(r.ctx, name)
+		if err != nil {
+			return err
+		}
+		writeJSON(w, http.StatusOK, snapshot.Info)
+		return nil
+	case len(r.segments) == 2 && r.Method == http.MethodDelete:
+		if err := s.authorize(r.principal, dataset, ActionDestroy); err != nil {
+			return err
+		}
+		snapshot, err := zfs.GetSnapshot(r.ctx, name)
+		if err != nil {
+			return err
+		}
+		if err := snapshot.Destroy(r.ctx, zfs.DestroyDefault); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	case len(r.segments) == 2:
+		return methodNotAllowed(r)
+	case len(r.segments) == 3 && action == "rollback":
+		return s.serveRollback(w, r, name, dataset)
+	case len(r.segments) == 3 && action == "clone":
+		return s.serveClone(w, r, name, dataset)
+	case len(r.segments) >= 3 && len(r.segments) <= 4 && action == "holds":
+		return s.serveHolds(w, r, name, dataset)
+	case len(r.segments) == 3 && action == "send":
+		return s.serveSend(w, r, name, dataset)
+	case len(r.segments) == 3 && action == "receive":
+		return s.serveReceive(w, r, name, dataset)
+	default:
+		return newError(http.StatusNotFound, "unknown path %q", r.URL.Path)
+	}
+}
+
+func (s *Server) serveRollback(w http.ResponseWriter, r *request, name, dataset string) error {
+	if r.Method != http.MethodPost {
+		return methodNotAllowed(r)
+	}
+	if err := s.authorize(r.principal, dataset, ActionRollback); err != nil {
+		return err
+	}
+	// snapshots newer than the one rolled back to are destroyed
+	if err := s.authorize(r.principal, dataset, ActionDestroy); err != nil {
+		return err
+	}
+	snapshot, err := zfs.GetSnapshot(r.ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := snapshot.Rollback(r.ctx); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *Server) serveClone(w http.ResponseWriter, r *request, name, dataset string) error {
+	if r.Method != http.MethodPost {
+		return methodNotAllowed(r)
+	}
+	var body CloneRequest
+	if err := decodeJSON(r, &body); err != nil {
+		return err
+	}
+	if err := validateName("filesystem", body.Name); err != nil {
+		return err
+	}
+	if err := validateProperties(body.Properties); err != nil {
+		return err
+	}
+	if err := s.authorize(r.principal, dataset, ActionClone); err != nil {
+		return err
+	}
+	if err := s.authorize(r.principal, body.Name, ActionCreate); err != nil {
+		return err
+	}
+	snapshot, err := zfs.GetSnapshot(r.ctx, name)
+	if err != nil {
+		return err
+	}
+	fs, err := snapshot.Clone(r.ctx, body.Name, zfs.CloneOptions{Properties: body.Properties})
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusCreated, fs.Info)
+	return nil
+}
+
+func (s *Server) serveHolds(w http.ResponseWriter, r *request, name, dataset string) error {
+	action := ActionHold
+	if len(r.segments) == 3 && r.Method == http.MethodGet {
+		action = ActionRead
+	}
+	if err := s.authorize(r.principal, dataset, action); err != nil {
+		return err
+	}
+	snapshot, err := zfs.GetSnapshot(r.ctx, name)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(r.segments) == 3 && r.Method == http.MethodGet:
+		holds, err := snapshot.Holds(r.ctx)
+		if err != nil {
+			return err
+		}
+		writeJSON(w, http.StatusOK, holds)
+		return nil
+	case len(r.segments) == 3 && r.Method == http.MethodPost:
+		var body HoldRequest
+		if err := decodeJSON(r, &body); err != nil {
+			return err
+		}
+		if err := validateName("tag", body.Tag); err != nil {
+			return err
+		}
+		if err := snapshot.Hold(r.ctx, body.Tag); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	case len(r.segments) == 4 && r.Method == http.MethodDelete:
+		if err := snapshot.Release(r.ctx, r.segments[3]); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	default:
+		return methodNotAllowed(r)
+	}
+}
+
+func (s *Server) serveSend(w http.ResponseWriter, r *request, name, dataset string) error {
+	if r.Method != http.MethodGet {
+		return methodNotAllowed(r)
+	}
+	if err := s.authorize(r.principal, dataset, ActionSend); err != nil {
+		return err
+	}
+	snapshot, err := zfs.GetSnapshot(r.ctx, name)
+	if err != nil {
+		return err
+	}
+
+	query := r.URL.Query()
+	options := zfs.SendOptions{
+		Raw:        query.Get("raw") == "true",
+		Properties: query.Get("props") == "true",
+	}
+	if from := query.Get("from"); from != "" {
+		if err := validateName("snapshot", from); err != nil {
+			return err
+		}
+		if datasetOf(from) != dataset {
+			return newError(http.StatusBadRequest, "snapshot %q doesn't belong to %q", from, dataset)
+		}
+		if options.IncrementFrom, err = zfs.GetSnapshot(r.ctx, from); err != nil {
+			return err
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	if err := snapshot.Send(r.ctx, options, nopCloser{Writer: w}); err != nil {
+		// status has been already sent so the only thing left is to log the error, client receives truncated stream
+		logger.Get(r.ctx).Error("Sending snapshot failed", zap.String("snapshot", name), zap.Error(err))
+	}
+	return nil
+}
+
+func (s *Server) serveReceive(w http.ResponseWriter, r *request, name, dataset string) error {
+	if r.Method != http.MethodPost {
+		return methodNotAllowed(r)
+	}
+	if err := s.authorize(r.principal, dataset, ActionReceive); err != nil {
+		return err
+	}
+	stream, err := checkStream(r.Body)
+	if err != nil {
+		return err
+	}
+
+	_, err = zfs.GetFilesystem(r.ctx, dataset)
+	switch {
+	case zfs.IsNotExist(err):
+		if err := s.authorize(r.principal, dataset, ActionCreate); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	}
+
+	snapshot, err := zfs.ReceiveSnapshotWithOptions(r.ctx, io.NopCloser(stream), name, zfs.ReceiveOptions{
+		NoMount:           true,
+		ExcludeProperties: hostProperties,
+	})
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusCreated, snapshot.Info)
+	return nil
+}
+
+func (s *Server) servePools(w http.ResponseWriter, r *request) error {
+	if r.Method != http.MethodGet {
+		return methodNotAllowed(r)
+	}
+
+	switch {
+	case len(r.segments) == 1:
+		pools, err := zfs.Pools(r.ctx)
+		if err != nil {
+			return err
+		}
+		statuses := []*zfs.PoolStatus{}
+		for _, pool := range pools {
+			if !s.allowed(r.principal, pool.Name, ActionRead) {
+				continue
+			}
+			status, err := pool.Status(r.ctx)
+			if err != nil {
+				// pools which can't report their status, e.g. suspended ones, are skipped, not to hide the other ones
+				logger.Get(r.ctx).Warn("Reading pool status failed", zap.String("pool", pool.Name), zap.Error(err))
+				continue
+			}
+			statuses = append(statuses, status)
+		}
+		writeJSON(w, http.StatusOK, statuses)
+		return nil
+	case len(r.segments) == 2 || (len(r.segments) == 3 && r.segments[2] == "properties"):
+		if err := s.authorize(r.principal, r.segments[1], ActionRead); err != nil {
+			return err
+		}
+		pool, err := zfs.GetPool(r.ctx, r.segments[1])
+		if err != nil {
+			return err
+		}
+		if len(r.segments) == 3 {
+			props, err := pool.Properties(r.ctx)
+			if err != nil {
+				return err
+			}
+			writeJSON(w, http.StatusOK, props)
+			return nil
+		}
+		status, err := pool.Status(r.ctx)
+		if err != nil {
+			return err
+		}
+		writeJSON(w, http.StatusOK, status)
+		return nil
+	default:
+		return newError(http.StatusNotFound, "unknown path %q", r.URL.Path)
+	}
+}
+
+func (s *Server) allowed(principal, dataset string, action Action) bool {
+	for _, rule := range s.config.Rules {
+		if rule.allows(principal, dataset, action) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) authorize(principal, dataset string, action Action) error {
+	if !s.allowed(principal, dataset, action) {
+		return newError(http.StatusForbidden, "action %q on %q is not permitted", action, dataset)
+	}
+	return nil
+}
+
+// authorizeMissingAncestors authorizes creation of the ancestors of the dataset which don't exist yet
+func (s *Server) authorizeMissingAncestors(r *request, name string) error {
+	for parent := path.Dir(name); strings.Contains(parent, "/"); parent = path.Dir(parent) {
+		_, err := zfs.GetFilesystem(r.ctx, parent)
+		switch {
+		case err == nil:
+			return nil
+		case !zfs.IsNotExist(err):
+			return err
+		}
+		if err := s.authorize(r.principal, parent, ActionCreate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// authorizeDescendants authorizes action on all the filesystems and volumes below the dataset.
+// Snapshots are authorized by the names of their datasets so they are covered too.
+func (s *Server) authorizeDescendants(r *request, name string, action Action) error {
+	filesystems, err := zfs.Filesystems(r.ctx)
+	if err != nil {
+		return err
+	}
+	volumes, err := zfs.Volumes(r.ctx)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(filesystems)+len(volumes))
+	for _, fs := range filesystems {
+		names = append(names, fs.Info.Name)
+	}
+	for _, volume := range volumes {
+		names = append(names, volume.Info.Name)
+	}
+	for _, n := range names {
+		if strings.HasPrefix(n, name+"/") {
+			if err := s.authorize(r.principal, n, action); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateName rejects empty names and names which would be parsed by zfs as options
+func validateName(kind, name string) error {
+	if name == "" || strings.HasPrefix(name, "-") {
+		return newError(http.StatusBadRequest, "invalid %s %q", kind, name)
+	}
+	return nil
+}
+
+// validateProperty rejects properties affecting the host and names which couldn't be passed to zfs as key=value
+func validateProperty(key string) error {
+	if err := validateName("property", key); err != nil {
+		return err
+	}
+	if strings.Contains(key, "=") {
+		return newError(http.StatusBadRequest, "invalid property %q", key)
+	}
+	for _, p := range hostProperties {
+		if strings.EqualFold(key, p) {
+			return newError(http.StatusBadRequest, "property %q can't be set", key)
+		}
+	}
+	return nil
+}
+
+func validateProperties(properties map[string]string) error {
+	for key := range properties {
+		if err := validateProperty(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func methodNotAllowed(r *request) error {
+	return newError(http.StatusMethodNotAllowed, "method %s is not allowed for %q", r.Method, r.URL.Path)
+}
+
+func decodeJSON(r *request, v interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return httpError{status: http.StatusBadRequest, err: errors.Wrap(err, "invalid request body")}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func snapshotInfos(snapshots []*zfs.Snapshot) []zfs.Info {
+	infos := make([]zfs.Info, 0, len(snapshots))
+	for _, s := range snapshots {
+		infos = append(infos, s.Info)
+	}
+	return infos
+}
+
+// valuesContext takes values from values context first, cancellation and deadline come from the embedded one
+type valuesContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c valuesContext) Value(key interface{}) interface{} {
+	if v := c.values.Value(key); v != nil {
+		return v
+	}
+	return c.Context.Value(key)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
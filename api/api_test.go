@@ -0,0 +1,202 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/outofforest/logger"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T, rules ...Rule) *Server {
+	ctx := logger.WithLogger(context.Background(), logger.New(logger.DefaultConfig))
+	s, err := New(ctx, Config{
+		Authenticate: func(r *http.Request) (string, error) {
+			principal := r.Header.Get("X-Principal")
+			if principal == "" {
+				return "", errors.New("principal not provided")
+			}
+			return principal, nil
+		},
+		Rules: rules,
+	})
+	require.NoError(t, err)
+	return s
+}
+
+func TestServerErrors(t *testing.T) {
+	s := newTestServer(t, Rule{Principal: "alice", Dataset: "tank/alice", Actions: []Action{ActionRead}})
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		principal string
+		body      string
+		status    int
+	}{
+		{name: "unauthenticated", method: http.MethodGet, path: "/filesystems/tank%2Falice", status: http.StatusUnauthorized},
+		{name: "unknownPath", method: http.MethodGet, path: "/volumes", principal: "alice", status: http.StatusNotFound},
+		{name: "unknownSubpath", method: http.MethodGet, path: "/filesystems/tank%2Falice/clones", principal: "alice",
+			status: http.StatusNotFound},
+		{name: "methodNotAllowed", method: http.MethodPatch, path: "/filesystems/tank%2Falice", principal: "alice",
+			status: http.StatusMethodNotAllowed},
+		{name: "poolMethodNotAllowed", method: http.MethodPost, path: "/pools", principal: "alice",
+			status: http.StatusMethodNotAllowed},
+		{name: "otherPrincipal", method: http.MethodGet, path: "/filesystems/tank%2Falice", principal: "bob",
+			status: http.StatusForbidden},
+		{name: "otherDataset", method: http.MethodGet, path: "/filesystems/tank%2Fbob", principal: "alice",
+			status: http.StatusForbidden},
+		{name: "actionNotPermitted", method: http.MethodDelete, path: "/filesystems/tank%2Falice", principal: "alice",
+			status: http.StatusForbidden},
+		{name: "createNotPermitted", method: http.MethodPost, path: "/filesystems", principal: "alice",
+			body: `{"name":"tank/alice/child"}`, status: http.StatusForbidden},
+		{name: "invalidBody", method: http.MethodPost, path: "/filesystems", principal: "alice",
+			body: `{"unknown":true}`, status: http.StatusBadRequest},
+		{name: "notSnapshot", method: http.MethodGet, path: "/snapshots/tank%2Falice", principal: "alice",
+			status: http.StatusBadRequest},
+		{name: "snapshotOfOtherDataset", method: http.MethodGet, path: "/snapshots/tank%2Fbob@a", principal: "alice",
+			status: http.StatusForbidden},
+		{name: "cloneNotPermitted", method: http.MethodPost, path: "/snapshots/tank%2Falice@a/clone", principal: "alice",
+			body: `{"name":"tank/alice2"}`, status: http.StatusForbidden},
+		{name: "sendNotPermitted", method: http.MethodGet, path: "/snapshots/tank%2Falice@a/send", principal: "alice",
+			status: http.StatusForbidden},
+		{name: "optionFilesystem", method: http.MethodGet, path: "/filesystems/-r", principal: "alice",
+			status: http.StatusBadRequest},
+		{name: "emptyFilesystem", method: http.MethodGet, path: "/filesystems//properties/used", principal: "alice",
+			status: http.StatusBadRequest},
+		{name: "optionProperty", method: http.MethodGet, path: "/filesystems/tank%2Falice/properties/-H",
+			principal: "alice", status: http.StatusBadRequest},
+		{name: "optionTag", method: http.MethodDelete, path: "/snapshots/tank%2Falice@a/holds/-r", principal: "alice",
+			status: http.StatusBadRequest},
+		{name: "optionCreate", method: http.MethodPost, path: "/filesystems", principal: "alice",
+			body: `{"name":"-p"}`, status: http.StatusBadRequest},
+		{name: "optionClone", method: http.MethodPost, path: "/snapshots/tank%2Falice@a/clone", principal: "alice",
+			body: `{"name":"-p"}`, status: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			if test.principal != "" {
+				req.Header.Set("X-Principal", test.principal)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			require.Equal(t, test.status, rec.Code)
+			require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			var body Error
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			require.NotEmpty(t, body.Error)
+		})
+	}
+}
+
+func TestHostPropertiesRejected(t *testing.T) {
+	s := newTestServer(t, Rule{Principal: "alice", Dataset: "tank/alice*"})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{name: "set", method: http.MethodPut, path: "/filesystems/tank%2Falice/properties/mountpoint",
+			body: `{"value":"/etc"}`},
+		{name: "setUpperCase", method: http.MethodPut, path: "/filesystems/tank%2Falice/properties/SETUID",
+			body: `{"value":"on"}`},
+		{name: "setAssignment", method: http.MethodPut, path: "/filesystems/tank%2Falice/properties/test:a=b",
+			body: `{"value":"on"}`},
+		{name: "create", method: http.MethodPost, path: "/filesystems",
+			body: `{"name":"tank/alice/child","properties":{"exec":"on"}}`},
+		{name: "createAssignment", method: http.MethodPost, path: "/filesystems",
+			body: `{"name":"tank/alice/child","properties":{"test:a=devices":"on"}}`},
+		{name: "clone", method: http.MethodPost, path: "/snapshots/tank%2Falice@a/clone",
+			body: `{"name":"tank/alice2","properties":{"sharenfs":"on"}}`},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			req.Header.Set("X-Principal", "alice")
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			// request must be rejected before zfs is called, otherwise status would be 404 or 500
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
+
+func TestUnauthorizedMessage(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/filesystems", nil))
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	var body Error
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "unauthorized", body.Error)
+}
+
+func TestRollbackRequiresDestroy(t *testing.T) {
+	s := newTestServer(t, Rule{Principal: "alice", Dataset: "tank/alice", Actions: []Action{ActionRollback}})
+
+	req := httptest.NewRequest(http.MethodPost, "/snapshots/tank%2Falice@a/rollback", nil)
+	req.Header.Set("X-Principal", "alice")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestRuleAllows(t *testing.T) {
+	rules := []Rule{
+		{Principal: AnyPrincipal, Dataset: "tank/public*", Actions: []Action{ActionRead}},
+		{Principal: "backup", Dataset: "tank/*", Actions: []Action{ActionRead, ActionSend, ActionHold}},
+		{Principal: "admin", Dataset: "*"},
+	}
+	s := newTestServer(t, rules...)
+
+	require.True(t, s.allowed("anyone", "tank/public", ActionRead))
+	require.True(t, s.allowed("anyone", "tank/public-data", ActionRead))
+	require.False(t, s.allowed("anyone", "tank/public", ActionDestroy))
+	require.False(t, s.allowed("anyone", "tank/private", ActionRead))
+	require.True(t, s.allowed("backup", "tank/private", ActionSend))
+	require.False(t, s.allowed("backup", "tank/private/child", ActionSend))
+	require.False(t, s.allowed("backup", "tank/private", ActionReceive))
+	require.True(t, s.allowed("admin", "tank", ActionDestroy))
+	require.False(t, s.allowed("admin", "tank/child", ActionDestroy))
+}
+
+func TestInvalidRule(t *testing.T) {
+	_, err := New(context.Background(), Config{Rules: []Rule{{Principal: AnyPrincipal, Dataset: "tank/["}}})
+	require.Error(t, err)
+}
+
+func TestDatasetOf(t *testing.T) {
+	require.Equal(t, "tank/data", datasetOf("tank/data@snap"))
+	require.Equal(t, "tank/data", datasetOf("tank/data"))
+}
+
+func TestErrorResponse(t *testing.T) {
+	status, message := errorResponse(newError(http.StatusForbidden, "action %q is not permitted", ActionRead))
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, `action "read" is not permitted`, message)
+
+	status, message = errorResponse(errors.WithStack(newError(http.StatusBadRequest, "invalid")))
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "invalid", message)
+
+	status, message = errorResponse(errors.New("exit status 1 => zfs list -H tank/secret: permission denied"))
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, "Internal Server Error", message)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Action is the operation performed on dataset
+type Action string
+
+// Actions
+const (
+	ActionRead     Action = "read"
+	ActionCreate   Action = "create"
+	ActionDestroy  Action = "destroy"
+	ActionProperty Action = "property"
+	ActionSnapshot Action = "snapshot"
+	ActionRollback Action = "rollback"
+	ActionClone    Action = "clone"
+	ActionHold     Action = "hold"
+	ActionSend     Action = "send"
+	ActionReceive  Action = "receive"
+)
+
+// AnyPrincipal matches all the principals in rules
+const AnyPrincipal = "*"
+
+// AuthenticateFunc authenticates the request and returns the principal sending it.
+// Returned error causes request to be rejected with 401 status.
+type AuthenticateFunc func(r *http.Request) (string, error)
+
+// Rule grants permissions to perform actions on datasets
+type Rule struct {
+	// Principal is the principal rule applies to, AnyPrincipal matches all of them
+	Principal string
+
+	// Dataset is the pattern of dataset names rule applies to, it uses the syntax of path.Match.
+	// Snapshots are matched by the name of their filesystem, pools by their name.
+	// Operations affecting other datasets, like recursive destruction or creation of missing parents,
+	// require the action to be permitted on each of them.
+	Dataset string
+
+	// Actions are the permitted actions, all the actions are permitted if empty
+	Actions []Action
+}
+
+func (r Rule) allows(principal, dataset string, action Action) bool {
+	if r.Principal != AnyPrincipal && r.Principal != principal {
+		return false
+	}
+	if matched, _ := path.Match(r.Dataset, dataset); !matched {
+		return false
+	}
+	if len(r.Actions) == 0 {
+		return true
+	}
+	for _, a := range r.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+func validateRules(rules []Rule) error {
+	for _, r := range rules {
+		if _, err := path.Match(r.Dataset, ""); err != nil {
+			return errors.Wrapf(err, "invalid dataset pattern %q", r.Dataset)
+		}
+	}
+	return nil
+}
+
+// datasetOf returns the name of filesystem snapshot belongs to
+func datasetOf(name string) string {
+	dataset, _, _ := strings.Cut(name, "@")
+	return dataset
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// streamMagic is the magic number stored in the header of the send stream
+	streamMagic uint64 = 0x2F5bacbac
+
+	// streamCompound is the type of header of the stream produced by zfs send -R, -I and -p
+	streamCompound uint64 = 0x2
+
+	// streamRecordSize is the size of the record starting the stream
+	streamRecordSize = 312
+
+	// maxStreamPayload is the maximum accepted size of the header of compound stream
+	maxStreamPayload = 16 << 20
+
+	// nvEncodeXDR is the encoding of the nvlist stored in the header of compound stream
+	nvEncodeXDR = 0x1
+
+	// streamNotRecursive is the flag added by zfs send to the header of compound stream if -R is not used
+	streamNotRecursive = "not_recursive"
+)
+
+// hostProperties are properties which could affect the host, clients are not allowed to set them
+// and they are not taken from received streams
+var hostProperties = []string{
+	"canmount",
+	"devices",
+	"exec",
+	"mountpoint",
+	"setuid",
+	"sharenfs",
+	"sharesmb",
+}
+
+// checkStream verifies that stream contains single dataset, replication streams are rejected
+// because they might create datasets not covered by the authorization rules.
+// Returned reader produces the entire stream, including the header read by the check.
+func checkStream(stream io.Reader) (io.Reader, error) {
+	record := make([]byte, streamRecordSize)
+	if _, err := io.ReadFull(stream, record); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, newError(http.StatusBadRequest, "stream is too short")
+		}
+		return nil, httpError{status: http.StatusBadRequest, err: err}
+	}
+
+	// streams are written in the byte order of the sender
+	var order binary.ByteOrder
+	switch {
+	case binary.LittleEndian.Uint64(record[8:]) == streamMagic:
+		order = binary.LittleEndian
+	case binary.BigEndian.Uint64(record[8:]) == streamMagic:
+		order = binary.BigEndian
+	default:
+		return nil, newError(http.StatusBadRequest, "invalid stream")
+	}
+	if order.Uint32(record) != 0 {
+		return nil, newError(http.StatusBadRequest, "stream doesn't start with begin record")
+	}
+	if order.Uint64(record[16:])&0x3 != streamCompound {
+		return io.MultiReader(bytes.NewReader(record), stream), nil
+	}
+
+	// compound header is followed by nvlist describing the stream, it tells if -R was used
+	size := order.Uint32(record[4:])
+	if size > maxStreamPayload {
+		return nil, newError(http.StatusBadRequest, "stream header is too large")
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(stream, payload); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, newError(http.StatusBadRequest, "stream is too short")
+		}
+		return nil, httpError{status: http.StatusBadRequest, err: err}
+	}
+	recursive, err := isRecursive(payload)
+	if err != nil {
+		return nil, err
+	}
+	if recursive {
+		return nil, newError(http.StatusBadRequest, "replication streams are not accepted")
+	}
+	return io.MultiReader(bytes.NewReader(record), bytes.NewReader(payload), stream), nil
+}
+
+// isRecursive checks if packed nvlist stored in the header of compound stream lacks the not_recursive flag.
+// Only the top-level pairs are scanned, each of them starts with its encoded size so the values are skipped.
+func isRecursive(payload []byte) (bool, error) {
+	// encoding header is followed by version and flags of the nvlist
+	const pairsOffset = 12
+	if len(payload) < pairsOffset || payload[0] != nvEncodeXDR {
+		return false, newError(http.StatusBadRequest, "invalid stream header")
+	}
+
+	for offset := pairsOffset; ; {
+		if len(payload)-offset < 8 {
+			return false, newError(http.StatusBadRequest, "invalid stream header")
+		}
+		// XDR is big-endian regardless of the sender
+		size := binary.BigEndian.Uint32(payload[offset:])
+		if size == 0 {
+			return true, nil
+		}
+		if size < 12 || uint64(size) > uint64(len(payload)-offset) {
+			return false, newError(http.StatusBadRequest, "invalid stream header")
+		}
+		pair := payload[offset : offset+int(size)]
+		nameSize := binary.BigEndian.Uint32(pair[8:])
+		if uint64(nameSize) > uint64(len(pair)-12) {
+			return false, newError(http.StatusBadRequest, "invalid stream header")
+		}
+		if string(pair[12:12+nameSize]) == streamNotRecursive {
+			return false, nil
+		}
+		offset += int(size)
+	}
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"testing"
+
+	"github.com/outofforest/go-zfs/v3"
+	"github.com/outofforest/logger"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func streamHeader(order binary.ByteOrder, recordType uint32, magic, versionInfo uint64, payload []byte) []byte {
+	header := make([]byte, streamRecordSize, streamRecordSize+len(payload))
+	order.PutUint32(header, recordType)
+	order.PutUint32(header[4:], uint32(len(payload)))
+	order.PutUint64(header[8:], magic)
+	order.PutUint64(header[16:], versionInfo)
+	return append(header, payload...)
+}
+
+func xdrString(s string) []byte {
+	b := make([]byte, 4, 4+len(s)+3)
+	binary.BigEndian.PutUint32(b, uint32(len(s)))
+	b = append(b, s...)
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// nvPair encodes nvpair the way it is done by nvlist_pack with NV_ENCODE_XDR
+func nvPair(name string, dataType, elements uint32, value []byte) []byte {
+	pair := make([]byte, 8)
+	pair = append(pair, xdrString(name)...)
+	pair = binary.BigEndian.AppendUint32(pair, dataType)
+	pair = binary.BigEndian.AppendUint32(pair, elements)
+	pair = append(pair, value...)
+	binary.BigEndian.PutUint32(pair, uint32(len(pair)))
+	binary.BigEndian.PutUint32(pair[4:], uint32(len(pair)))
+	return pair
+}
+
+// compoundPayload returns the header of compound stream like the one produced by zfs send
+func compoundPayload(notRecursive bool) []byte {
+	payload := []byte{nvEncodeXDR, 0x1, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x1}
+	payload = append(payload, nvPair("tosnap", 9, 1, xdrString("snap"))...)
+	if notRecursive {
+		payload = append(payload, nvPair(streamNotRecursive, 1, 0, nil)...)
+	}
+	// nested nvlist is skipped as a whole, names inside it are not taken into account
+	payload = append(payload, nvPair("fss", 19, 1, nvPair(streamNotRecursive, 1, 0, nil))...)
+	return append(payload, make([]byte, 8)...)
+}
+
+func TestCheckStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream []byte
+		valid  bool
+	}{
+		{name: "littleEndian", stream: streamHeader(binary.LittleEndian, 0, streamMagic, 0x1, nil), valid: true},
+		{name: "bigEndian", stream: streamHeader(binary.BigEndian, 0, streamMagic, 0x1, nil), valid: true},
+		{name: "compound", stream: streamHeader(binary.LittleEndian, 0, streamMagic, 0x2, compoundPayload(true)),
+			valid: true},
+		{name: "compoundBigEndian", stream: streamHeader(binary.BigEndian, 0, streamMagic, 0x2, compoundPayload(true)),
+			valid: true},
+		{name: "replication", stream: streamHeader(binary.LittleEndian, 0, streamMagic, 0x2, compoundPayload(false))},
+		{name: "replicationBigEndian", stream: streamHeader(binary.BigEndian, 0, streamMagic, 0x2,
+			compoundPayload(false))},
+		{name: "compoundTruncated", stream: streamHeader(binary.LittleEndian, 0, streamMagic, 0x2,
+			compoundPayload(true))[:streamRecordSize+16]},
+		{name: "compoundInvalidEncoding", stream: streamHeader(binary.LittleEndian, 0, streamMagic, 0x2,
+			make([]byte, 32))},
+		{name: "notBegin", stream: streamHeader(binary.LittleEndian, 1, streamMagic, 0x1, nil)},
+		{name: "invalidMagic", stream: streamHeader(binary.LittleEndian, 0, 0x1234, 0x1, nil)},
+		{name: "short", stream: []byte{0x0, 0x0}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			data := append(append([]byte{}, test.stream...), "data"...)
+			stream, err := checkStream(bytes.NewReader(data))
+			if test.valid {
+				require.NoError(t, err)
+				// header must not be lost
+				received, err := io.ReadAll(stream)
+				require.NoError(t, err)
+				assert.Equal(t, data, received)
+				return
+			}
+			var hErr httpError
+			require.True(t, errors.As(err, &hErr))
+			assert.Equal(t, http.StatusBadRequest, hErr.status)
+		})
+	}
+}
+
+func TestReceiveRejectsReplicationStream(t *testing.T) {
+	s := newTestServer(t, Rule{Principal: "alice", Dataset: "tank/alice"})
+
+	req := httptest.NewRequest(http.MethodPost, "/snapshots/tank%2Falice@snap/receive",
+		bytes.NewReader(streamHeader(binary.LittleEndian, 0, streamMagic, 0x2, compoundPayload(false))))
+	req.Header.Set("X-Principal", "alice")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestSendReceive(t *testing.T) {
+	t.Cleanup(cleanZFS)
+	cleanZFS()
+
+	require.NoError(t, exec.Command("modprobe", "zfs").Run())
+	require.NoError(t, exec.Command("modprobe", "brd", "rd_nr=1", "rd_size=102400").Run())
+	require.NoError(t, exec.Command("zpool", "create", "goapi", "/dev/ram0").Run())
+
+	ctx := logger.WithLogger(context.Background(), logger.New(logger.DefaultConfig))
+	s, err := New(ctx, Config{Rules: []Rule{{Principal: AnyPrincipal, Dataset: "goapi*"}}})
+	require.NoError(t, err)
+
+	fs, err := zfs.CreateFilesystem(ctx, "goapi/src", zfs.CreateFilesystemOptions{
+		Properties: map[string]string{"test:prop": "value"},
+	})
+	require.NoError(t, err)
+	_, err = fs.Snapshot(ctx, "s1")
+	require.NoError(t, err)
+	_, err = fs.Snapshot(ctx, "s2")
+	require.NoError(t, err)
+
+	send := func(path string) []byte {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec.Body.Bytes()
+	}
+	receive := func(name string, stream []byte) int {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/snapshots/"+name+"/receive", bytes.NewReader(stream)))
+		return rec.Code
+	}
+
+	// streams with properties are compound ones, they must be accepted by the server producing them
+	assert.Equal(t, http.StatusCreated, receive("goapi%2Fdst@s1", send("/snapshots/goapi%2Fsrc@s1/send?props=true")))
+	assert.Equal(t, http.StatusCreated, receive("goapi%2Fdst@s2",
+		send("/snapshots/goapi%2Fsrc@s2/send?props=true&from=goapi%2Fsrc%40s1")))
+
+	dst, err := zfs.GetFilesystem(ctx, "goapi/dst")
+	require.NoError(t, err)
+	value, exists, err := dst.GetProperty(ctx, "test:prop")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, "value", value)
+
+	replication, err := exec.Command("zfs", "send", "-R", "goapi/src@s2").Output()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, receive("goapi%2Freplica@s2", replication))
+}
+
+func cleanZFS() {
+	_ = exec.Command("zpool", "destroy", "goapi").Run()
+	_ = exec.Command("rmmod", "brd").Run()
+}
@@ -0,0 +1,98 @@
+package zfs
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Checkpoint states
+const (
+	CheckpointExists     = "exists"
+	CheckpointDiscarding = "discarding"
+)
+
+// CheckpointStatus is the status of ZPool checkpoint
+type CheckpointStatus struct {
+	// State is either CheckpointExists or CheckpointDiscarding
+	State string
+
+	Created time.Time
+
+	// Space is the space consumed by the checkpoint
+	Space uint64
+
+	// Text is the original description of the checkpoint, it is empty if status was reported in JSON
+	Text string
+}
+
+// Checkpoint creates checkpoint of ZPool, pool may be rewound to it during import.
+// Only one checkpoint may exist at a time.
+func (p *Pool) Checkpoint(ctx context.Context) error {
+	_, err := zpool(ctx, "checkpoint", p.Name)
+	return err
+}
+
+// DiscardCheckpoint discards checkpoint of ZPool, space is reclaimed in background
+func (p *Pool) DiscardCheckpoint(ctx context.Context) error {
+	_, err := zpool(ctx, "checkpoint", "-d", p.Name)
+	return err
+}
+
+// CheckpointStatus returns status of ZPool checkpoint, nil is returned if there is no checkpoint
+func (p *Pool) CheckpointStatus(ctx context.Context) (*CheckpointStatus, error) {
+	status, err := p.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if status.Checkpoint == nil {
+		return nil, nil
+	}
+
+	checkpoint := *status.Checkpoint
+	if checkpoint.Space == 0 {
+		// text output of zpool status reports space in human-readable format only
+		out, err := zpool(ctx, "get", "-H", "-p", "-o", "value", "checkpoint", p.Name)
+		if err != nil {
+			return nil, err
+		}
+		if err := setUint(&checkpoint.Space, out[0][0]); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return &checkpoint, nil
+}
+
+func parseCheckpointText(text string) *CheckpointStatus {
+	checkpoint := &CheckpointStatus{Text: text}
+	if strings.HasPrefix(text, "discarding") {
+		checkpoint.State = CheckpointDiscarding
+		return checkpoint
+	}
+
+	checkpoint.State = CheckpointExists
+	created, _, _ := strings.Cut(strings.TrimPrefix(text, "created "), ",")
+	if t, err := time.ParseInLocation(scanTimeLayout, created, time.Local); err == nil {
+		checkpoint.Created = t
+	}
+	return checkpoint
+}
+
+type jsonCheckpoint struct {
+	State     string   `json:"state"`
+	StartTime jsonUint `json:"start_time"`
+	Space     jsonUint `json:"space"`
+}
+
+func (c jsonCheckpoint) status() *CheckpointStatus {
+	checkpoint := &CheckpointStatus{
+		State: strings.ToLower(c.State),
+		Space: uint64(c.Space),
+	}
+	if c.StartTime > 0 {
+		checkpoint.Created = time.Unix(int64(c.StartTime), 0)
+	}
+	return checkpoint
+}
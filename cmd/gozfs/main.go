@@ -0,0 +1,331 @@
+// Package main provides command line tool exposing operations of the library.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/outofforest/go-zfs/v3"
+	"github.com/outofforest/logger"
+	"github.com/pkg/errors"
+)
+
+const snapshotTimeLayout = "20060102-150405"
+
+type command struct {
+	Usage string
+	Run   func(ctx context.Context, flags *flag.FlagSet, args []string, out io.Writer) error
+}
+
+var commands = map[string]command{
+	"list": {
+		Usage: "list [-t filesystem|snapshot] [dataset]",
+		Run:   list,
+	},
+	"snapshot": {
+		Usage: "snapshot [-prefix prefix] filesystem [name]",
+		Run:   snapshot,
+	},
+	"prune": {
+		Usage: "prune [-prefix prefix] [-keep-last n] [-keep-within duration] [-dry-run] filesystem",
+		Run:   prune,
+	},
+	"replicate": {
+		Usage: "replicate [-raw] [-props] [-force] source-filesystem target-filesystem",
+		Run:   replicate,
+	},
+	"send": {
+		Usage: "send [-raw] [-props] [-from snapshot] snapshot file|-",
+		Run:   send,
+	},
+	"receive": {
+		Usage: "receive file|- snapshot",
+		Run:   receive,
+	},
+	"status": {
+		Usage: "status [pool]",
+		Run:   status,
+	},
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("gozfs", flag.ContinueOnError)
+	verbose := flags.Bool("v", false, "log executed commands")
+	flags.Usage = func() {
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Fprintln(flags.Output(), "Usage: gozfs [-v] command [options] [arguments]")
+		fmt.Fprintln(flags.Output(), "Commands:")
+		for _, name := range names {
+			fmt.Fprintln(flags.Output(), "  "+commands[name].Usage)
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("command not specified")
+	}
+
+	cmd, exists := commands[flags.Arg(0)]
+	if !exists {
+		flags.Usage()
+		return errors.Errorf("unknown command %q", flags.Arg(0))
+	}
+
+	logConfig := logger.DefaultConfig
+	logConfig.Verbose = *verbose
+	ctx = logger.WithLogger(ctx, logger.New(logConfig))
+	if *verbose {
+		ctx = zfs.WithHooks(ctx, zfs.LogHook{})
+	}
+
+	cmdFlags := flag.NewFlagSet(flags.Arg(0), flag.ContinueOnError)
+	cmdFlags.Usage = func() {
+		fmt.Fprintln(cmdFlags.Output(), "Usage: gozfs "+cmd.Usage)
+		cmdFlags.PrintDefaults()
+	}
+	return cmd.Run(ctx, cmdFlags, flags.Args()[1:], out)
+}
+
+func list(ctx context.Context, flags *flag.FlagSet, args []string, out io.Writer) error {
+	datasetType := flags.String("t", "filesystem", "type of datasets: filesystem or snapshot")
+	if err := parseArgs(flags, args, 0, 1); err != nil {
+		return err
+	}
+
+	var infos []zfs.Info
+	switch *datasetType {
+	case "filesystem":
+		filesystems, err := zfs.Filesystems(ctx)
+		if err != nil {
+			return err
+		}
+		for _, fs := range filesystems {
+			infos = append(infos, fs.Info)
+		}
+	case "snapshot":
+		snapshots, err := zfs.Snapshots(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range snapshots {
+			infos = append(infos, s.Info)
+		}
+	default:
+		return errors.Errorf("unknown dataset type %q", *datasetType)
+	}
+
+	result := []zfs.Info{}
+	for _, info := range infos {
+		if flags.NArg() == 0 || belongsTo(info.Name, flags.Arg(0)) {
+			result = append(result, info)
+		}
+	}
+	return writeJSON(out, result)
+}
+
+func snapshot(ctx context.Context, flags *flag.FlagSet, args []string, out io.Writer) error {
+	prefix := flags.String("prefix", "", "prefix of the generated snapshot name")
+	if err := parseArgs(flags, args, 1, 2); err != nil {
+		return err
+	}
+
+	name := flags.Arg(1)
+	if name == "" {
+		name = *prefix + time.Now().UTC().Format(snapshotTimeLayout)
+	}
+	fs, err := zfs.GetFilesystem(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	s, err := fs.Snapshot(ctx, name)
+	if err != nil {
+		return err
+	}
+	return writeJSON(out, s.Info)
+}
+
+func prune(ctx context.Context, flags *flag.FlagSet, args []string, out io.Writer) error {
+	var policy zfs.PrunePolicy
+	flags.StringVar(&policy.Prefix, "prefix", "", "prune only snapshots with names starting with prefix")
+	flags.IntVar(&policy.KeepLast, "keep-last", 0, "number of the newest snapshots to keep")
+	flags.DurationVar(&policy.KeepWithin, "keep-within", 0, "keep snapshots created within the duration")
+	dryRun := flags.Bool("dry-run", false, "report snapshots without destroying them")
+	if err := parseArgs(flags, args, 1, 1); err != nil {
+		return err
+	}
+
+	fs, err := zfs.GetFilesystem(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	pruned, err := fs.Prune(ctx, policy, *dryRun)
+	if err != nil {
+		return err
+	}
+	result := make([]zfs.Info, 0, len(pruned))
+	for _, s := range pruned {
+		result = append(result, s.Info)
+	}
+	return writeJSON(out, result)
+}
+
+func replicate(ctx context.Context, flags *flag.FlagSet, args []string, out io.Writer) error {
+	var options zfs.ReplicateOptions
+	flags.BoolVar(&options.Raw, "raw", false, "send raw stream")
+	flags.BoolVar(&options.Properties, "props", false, "send properties")
+	flags.BoolVar(&options.Force, "force", false, "roll target back to the common snapshot if it has been modified")
+	if err := parseArgs(flags, args, 2, 2); err != nil {
+		return err
+	}
+
+	source, err := zfs.GetFilesystem(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	s, err := zfs.Replicate(ctx, source, flags.Arg(1), options)
+	if err != nil {
+		return err
+	}
+	return writeJSON(out, s.Info)
+}
+
+func send(ctx context.Context, flags *flag.FlagSet, args []string, out io.Writer) error {
+	var options zfs.SendOptions
+	flags.BoolVar(&options.Raw, "raw", false, "send raw stream")
+	flags.BoolVar(&options.Properties, "props", false, "send properties")
+	from := flags.String("from", "", "snapshot incremental stream is generated from")
+	if err := parseArgs(flags, args, 2, 2); err != nil {
+		return err
+	}
+
+	s, err := zfs.GetSnapshot(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *from != "" {
+		if options.IncrementFrom, err = zfs.GetSnapshot(ctx, *from); err != nil {
+			return err
+		}
+	}
+
+	if flags.Arg(1) == "-" {
+		return s.Send(ctx, options, nopCloser{Writer: out})
+	}
+	f, err := os.OpenFile(flags.Arg(1), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := s.Send(ctx, options, f); err != nil {
+		_ = os.Remove(flags.Arg(1))
+		return err
+	}
+	return writeJSON(out, s.Info)
+}
+
+func receive(ctx context.Context, flags *flag.FlagSet, args []string, out io.Writer) error {
+	if err := parseArgs(flags, args, 2, 2); err != nil {
+		return err
+	}
+
+	var input io.ReadCloser = io.NopCloser(os.Stdin)
+	if flags.Arg(0) != "-" {
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		input = f
+	}
+	s, err := zfs.ReceiveSnapshot(ctx, input, flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	return writeJSON(out, s.Info)
+}
+
+func status(ctx context.Context, flags *flag.FlagSet, args []string, out io.Writer) error {
+	if err := parseArgs(flags, args, 0, 1); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 1 {
+		pool, err := zfs.GetPool(ctx, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		s, err := pool.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return writeJSON(out, s)
+	}
+
+	pools, err := zfs.Pools(ctx)
+	if err != nil {
+		return err
+	}
+	statuses := make([]*zfs.PoolStatus, 0, len(pools))
+	for _, pool := range pools {
+		s, err := pool.Status(ctx)
+		if err != nil {
+			return err
+		}
+		statuses = append(statuses, s)
+	}
+	return writeJSON(out, statuses)
+}
+
+func parseArgs(flags *flag.FlagSet, args []string, minArgs, maxArgs int) error {
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < minArgs || flags.NArg() > maxArgs {
+		flags.Usage()
+		return errors.New("invalid number of arguments")
+	}
+	return nil
+}
+
+// belongsTo reports if dataset is the parent itself or its descendant
+func belongsTo(name, parent string) bool {
+	return name == parent || strings.HasPrefix(name, parent+"/") || strings.HasPrefix(name, parent+"@")
+}
+
+func writeJSON(out io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return errors.WithStack(encoder.Encode(v))
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunInvalidArgs(t *testing.T) {
+	ctx := context.Background()
+	out := &bytes.Buffer{}
+
+	require.Error(t, run(ctx, nil, out))
+	require.Error(t, run(ctx, []string{"unknown"}, out))
+	require.Error(t, run(ctx, []string{"snapshot"}, out))
+	require.Error(t, run(ctx, []string{"replicate", "tank/a"}, out))
+	require.Error(t, run(ctx, []string{"list", "-t", "volume"}, out))
+	assert.Empty(t, out.String())
+}
+
+func TestBelongsTo(t *testing.T) {
+	assert.True(t, belongsTo("tank/fs", "tank/fs"))
+	assert.True(t, belongsTo("tank/fs/child", "tank/fs"))
+	assert.True(t, belongsTo("tank/fs@snap", "tank/fs"))
+	assert.False(t, belongsTo("tank/fs2", "tank/fs"))
+}
@@ -0,0 +1,142 @@
+package zfs
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DiffChangeType is the type of change reported by Diff
+type DiffChangeType string
+
+// Diff change types
+const (
+	DiffAdded    DiffChangeType = "+"
+	DiffRemoved  DiffChangeType = "-"
+	DiffModified DiffChangeType = "M"
+	DiffRenamed  DiffChangeType = "R"
+)
+
+// DiffFileType is the type of file reported by Diff
+type DiffFileType string
+
+// Diff file types
+const (
+	DiffFileRegular     DiffFileType = "F"
+	DiffFileDirectory   DiffFileType = "/"
+	DiffFileSymlink     DiffFileType = "@"
+	DiffFileSocket      DiffFileType = "="
+	DiffFileFIFO        DiffFileType = "|"
+	DiffFileBlockDevice DiffFileType = "B"
+	DiffFileCharDevice  DiffFileType = "C"
+	DiffFileDoor        DiffFileType = ">"
+	DiffFileEventPort   DiffFileType = "P"
+)
+
+// DiffChange is a single change reported by Diff
+type DiffChange struct {
+	Time     time.Time
+	Type     DiffChangeType
+	FileType DiffFileType
+	Path     string
+	NewPath  string
+}
+
+// Diff streams changes between the snapshot and the other one to the changes channel.
+// If to is nil, snapshot is compared to the current state of its filesystem.
+// Channel is closed when diff is finished.
+func (d *Snapshot) Diff(ctx context.Context, to *Snapshot, changes chan<- DiffChange) error {
+	defer close(changes)
+
+	args := []string{"diff", "-F", "-H", "-t", d.Info.Name}
+	if to != nil {
+		args = append(args, to.Info.Name)
+	}
+	return zfsStream(ctx, args, func(line []string) error {
+		change, err := parseDiffLine(line)
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return errors.WithStack(ctx.Err())
+		case changes <- change:
+			return nil
+		}
+	})
+}
+
+func parseDiffLine(line []string) (DiffChange, error) {
+	if len(line) < 4 {
+		return DiffChange{}, errors.Errorf("invalid diff line: %q", strings.Join(line, "\t"))
+	}
+
+	t, err := parseDiffTime(line[0])
+	if err != nil {
+		return DiffChange{}, err
+	}
+	path, err := unescapeDiffPath(line[3])
+	if err != nil {
+		return DiffChange{}, err
+	}
+
+	change := DiffChange{
+		Time:     t,
+		Type:     DiffChangeType(line[1]),
+		FileType: DiffFileType(line[2]),
+		Path:     path,
+	}
+	if change.Type == DiffRenamed {
+		if len(line) < 5 {
+			return DiffChange{}, errors.Errorf("invalid diff line: %q", strings.Join(line, "\t"))
+		}
+		if change.NewPath, err = unescapeDiffPath(line[4]); err != nil {
+			return DiffChange{}, err
+		}
+	}
+	return change, nil
+}
+
+func parseDiffTime(value string) (time.Time, error) {
+	secStr, nsecStr, _ := strings.Cut(value, ".")
+	sec, err := strconv.ParseInt(secStr, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.WithStack(err)
+	}
+	var nsec int64
+	if nsecStr != "" {
+		nsecStr = (nsecStr + "000000000")[:9]
+		if nsec, err = strconv.ParseInt(nsecStr, 10, 64); err != nil {
+			return time.Time{}, errors.WithStack(err)
+		}
+	}
+	return time.Unix(sec, nsec), nil
+}
+
+// unescapeDiffPath decodes characters escaped by zfs diff in the \0ooo form
+func unescapeDiffPath(path string) (string, error) {
+	if !strings.Contains(path, `\`) {
+		return path, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] != '\\' {
+			b.WriteByte(path[i])
+			continue
+		}
+		if i+5 > len(path) {
+			return "", errors.Errorf("invalid escape sequence in path %q", path)
+		}
+		c, err := strconv.ParseUint(path[i+1:i+5], 8, 8)
+		if err != nil {
+			return "", errors.Wrapf(err, "invalid escape sequence in path %q", path)
+		}
+		b.WriteByte(byte(c))
+		i += 4
+	}
+	return b.String(), nil
+}
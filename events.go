@@ -0,0 +1,148 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const eventTimeLayout = "Jan 2 2006 15:04:05.000000000"
+
+// Event is the event reported by ZFS
+type Event struct {
+	Time time.Time
+
+	// Class is the class of the event, e.g. ereport.fs.zfs.checksum
+	Class string
+
+	Pool     string
+	PoolGUID uint64
+	VdevPath string
+	VdevGUID uint64
+	EID      uint64
+
+	// Payload contains all the fields of the event, names of nested fields are joined using dots
+	Payload map[string]string
+}
+
+// Events streams events reported by ZFS for the pool, or all the pools if pool is empty, to the channel.
+// Events already recorded by the kernel are delivered first, then function waits for new events until
+// context is canceled. Channel is closed when function returns.
+func Events(ctx context.Context, pool string, events chan<- Event) error {
+	defer close(events)
+
+	args := []string{"events", "-H", "-f", "-v"}
+	if pool != "" {
+		args = append(args, pool)
+	}
+
+	parser := &eventParser{}
+	return streamLines(ctx, func(ctx context.Context, stdout io.Writer) error {
+		return zpoolStdout(ctx, stdout, args...)
+	}, func(line []string) error {
+		event, err := parser.parse(strings.Join(line, "\t"))
+		if err != nil || event == nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return errors.WithStack(ctx.Err())
+		case events <- *event:
+			return nil
+		}
+	})
+}
+
+// eventParser parses the verbose output of zpool events
+type eventParser struct {
+	event  *Event
+	prefix []string
+}
+
+// parse consumes the line of output and returns the event once it is complete
+func (p *eventParser) parse(text string) (*Event, error) {
+	switch {
+	case strings.TrimSpace(text) == "":
+		event := p.event
+		p.event = nil
+		return event, nil
+	case text[0] != ' ' && text[0] != '\t':
+		var err error
+		p.event, err = parseEventHeader(text)
+		p.prefix = nil
+		return nil, err
+	case p.event == nil:
+		return nil, errors.Errorf("unexpected line in events: %q", text)
+	default:
+		var err error
+		p.prefix, err = p.event.addField(p.prefix, strings.TrimSpace(text))
+		return nil, err
+	}
+}
+
+func parseEventHeader(text string) (*Event, error) {
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return nil, errors.Errorf("invalid event header: %q", text)
+	}
+	t, err := time.ParseInLocation(eventTimeLayout, strings.Join(fields[:len(fields)-1], " "), time.Local)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid event header: %q", text)
+	}
+	return &Event{
+		Time:    t,
+		Class:   fields[len(fields)-1],
+		Payload: map[string]string{},
+	}, nil
+}
+
+func (e *Event) addField(prefix []string, text string) ([]string, error) {
+	if strings.HasPrefix(text, "(end ") {
+		if len(prefix) == 0 {
+			return nil, errors.Errorf("unexpected end of nested list: %q", text)
+		}
+		return prefix[:len(prefix)-1], nil
+	}
+
+	name, value, ok := strings.Cut(text, " = ")
+	if !ok {
+		return nil, errors.Errorf("invalid event field: %q", text)
+	}
+	if strings.HasPrefix(value, "(embedded nvlist)") {
+		return append(prefix, name), nil
+	}
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		value = value[1 : len(value)-1]
+	}
+	value = strings.TrimSpace(value)
+
+	if len(prefix) > 0 {
+		e.Payload[strings.Join(append(prefix, name), ".")] = value
+		return prefix, nil
+	}
+	e.Payload[name] = value
+
+	var err error
+	switch name {
+	case "pool":
+		e.Pool = value
+	case "pool_guid":
+		e.PoolGUID, err = parseEventUint(value)
+	case "vdev_path":
+		e.VdevPath = value
+	case "vdev_guid":
+		e.VdevGUID, err = parseEventUint(value)
+	case "eid":
+		e.EID, err = parseEventUint(value)
+	}
+	return prefix, err
+}
+
+func parseEventUint(value string) (uint64, error) {
+	v, err := strconv.ParseUint(value, 0, 64)
+	return v, errors.WithStack(err)
+}
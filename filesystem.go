@@ -2,11 +2,11 @@
 package zfs
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"math"
+	"strings"
 )
 
 const datasetFilesystem = "filesystem"
@@ -39,7 +39,25 @@ func GetFilesystem(ctx context.Context, name string) (*Filesystem, error) {
 // CreateFilesystemOptions stores options passed to CreateFilesystem function
 type CreateFilesystemOptions struct {
 	Properties map[string]string
-	Password   string
+
+	// Password is a shorthand for passphrase encryption with the key provided by StaticKeyProvider,
+	// it takes precedence over Encryption
+	Password string
+
+	// Encryption describes the encryption key of the filesystem
+	Encryption *KeyOptions
+
+	// Parents causes all the missing parent datasets to be created
+	Parents bool
+
+	// NoMount causes the filesystem not to be mounted after creation
+	NoMount bool
+}
+
+// CreateFilesystemPlan describes the filesystem which would be created by CreateFilesystem
+type CreateFilesystemPlan struct {
+	Name       string
+	Properties map[string]string
 }
 
 // CreateFilesystem creates a new ZFS filesystem with the specified name and
@@ -47,20 +65,63 @@ type CreateFilesystemOptions struct {
 // A full list of available ZFS properties may be found here:
 // https://www.freebsd.org/cgi/man.cgi?zfs(8).
 func CreateFilesystem(ctx context.Context, name string, options CreateFilesystemOptions) (*Filesystem, error) {
+	args, encryption := createFilesystemArgs(name, options)
+	var stdin io.Reader
+	if encryption != nil {
+		var err error
+		if stdin, err = encryption.stdin(ctx, name); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := zfsStdin(ctx, stdin, args...); err != nil {
+		return nil, err
+	}
+	return GetFilesystem(ctx, name)
+}
+
+// CreateFilesystemDryRun verifies that the filesystem may be created by CreateFilesystem and returns
+// its description without creating it.
+func CreateFilesystemDryRun(ctx context.Context, name string, options CreateFilesystemOptions) (CreateFilesystemPlan, error) {
+	args, _ := createFilesystemArgs(name, options)
+	out, err := zfs(ctx, append([]string{args[0], "-n", "-v", "-P"}, args[1:]...)...)
+	if err != nil {
+		return CreateFilesystemPlan{}, err
+	}
+
+	plan := CreateFilesystemPlan{Properties: map[string]string{}}
+	for _, line := range out {
+		switch {
+		case len(line) == 2 && line[0] == "create":
+			plan.Name = line[1]
+		case len(line) == 3 && line[0] == "property":
+			plan.Properties[line[1]] = line[2]
+		}
+	}
+	return plan, nil
+}
+
+func createFilesystemArgs(name string, options CreateFilesystemOptions) ([]string, *KeyOptions) {
 	args := []string{"create"}
+	if options.Parents {
+		args = append(args, "-p")
+	}
+	if options.NoMount {
+		args = append(args, "-u")
+	}
 	if len(options.Properties) > 0 {
 		args = append(args, propsSlice(options.Properties)...)
 	}
-	var stdin io.Reader
+	encryption := options.Encryption
 	if options.Password != "" {
-		args = append(args, "-o", "encryption=on", "-o", "keylocation=prompt", "-o", "keyformat=passphrase")
-		stdin = bytes.NewReader([]byte(options.Password + "\n" + options.Password))
+		encryption = &KeyOptions{
+			Format:   KeyFormatPassphrase,
+			Provider: StaticKeyProvider{Default: []byte(options.Password)},
+		}
 	}
-	args = append(args, name)
-	if _, err := zfsStdin(ctx, stdin, args...); err != nil {
-		return nil, err
+	if encryption != nil {
+		args = append(args, encryption.args(true)...)
 	}
-	return GetFilesystem(ctx, name)
+	return append(args, name), encryption
 }
 
 // Filesystem is a ZFS filesystem
@@ -76,6 +137,51 @@ func (d *Filesystem) Destroy(ctx context.Context, flags DestroyFlag) error {
 	return destroy(ctx, d.Info.Name, flags)
 }
 
+// DestroySnapshotsResult is the result of destroying many snapshots at once
+type DestroySnapshotsResult struct {
+	Snapshots []string
+	Reclaimed uint64
+}
+
+// DestroySnapshots destroys all the snapshots of the filesystem starting at snapshot named from
+// and ending at snapshot named to, both inclusive, in a single operation.
+// Empty from selects all the snapshots up to to, empty to selects all the snapshots starting at from.
+func (d *Filesystem) DestroySnapshots(ctx context.Context, from, to string, flags DestroyFlag) (DestroySnapshotsResult, error) {
+	return d.destroySnapshots(ctx, from+"%"+to, flags)
+}
+
+// DestroySnapshotsByName destroys snapshots of the filesystem with the provided names in a single operation.
+func (d *Filesystem) DestroySnapshotsByName(ctx context.Context, names []string, flags DestroyFlag) (DestroySnapshotsResult, error) {
+	if len(names) == 0 {
+		return DestroySnapshotsResult{}, nil
+	}
+	return d.destroySnapshots(ctx, strings.Join(names, ","), flags)
+}
+
+func (d *Filesystem) destroySnapshots(ctx context.Context, spec string, flags DestroyFlag) (DestroySnapshotsResult, error) {
+	args := append(destroyArgs(flags), "-v", "-p", d.Info.Name+"@"+spec)
+	out, err := zfs(ctx, args...)
+	if err != nil {
+		return DestroySnapshotsResult{}, err
+	}
+
+	result := DestroySnapshotsResult{Snapshots: make([]string, 0, len(out))}
+	for _, line := range out {
+		if len(line) != 2 {
+			continue
+		}
+		switch line[0] {
+		case "destroy":
+			result.Snapshots = append(result.Snapshots, line[1])
+		case "reclaim":
+			if err := setUint(&result.Reclaimed, line[1]); err != nil {
+				return DestroySnapshotsResult{}, err
+			}
+		}
+	}
+	return result, nil
+}
+
 // SetProperty sets a ZFS property on the receiving dataset.
 // A full list of available ZFS properties may be found here:
 // https://www.freebsd.org/cgi/man.cgi?zfs(8).
@@ -122,22 +228,128 @@ func (d *Filesystem) Children(ctx context.Context) ([]*Filesystem, error) {
 	return filesystems, nil
 }
 
+// MountOptions stores options passed to MountWithOptions method and MountAll function
+type MountOptions struct {
+	// Options are temporary mount options, e.g. ro
+	Options []string
+
+	// Overlay allows mounting on top of non-empty directory
+	Overlay bool
+
+	// LoadKey loads the key from its key location before mounting if it is not loaded yet
+	LoadKey bool
+
+	// KeyProvider is used to load the key if filesystem is encrypted and its key is not loaded yet,
+	// it is ignored by MountAll
+	KeyProvider KeyProvider
+}
+
+func (o MountOptions) args() []string {
+	args := []string{"mount"}
+	if len(o.Options) > 0 {
+		args = append(args, "-o", strings.Join(o.Options, ","))
+	}
+	if o.Overlay {
+		args = append(args, "-O")
+	}
+	if o.LoadKey {
+		args = append(args, "-l")
+	}
+	return args
+}
+
+// UnmountOptions stores options passed to UnmountWithOptions method and UnmountAll function
+type UnmountOptions struct {
+	// Force unmounts filesystem even if it is in use
+	Force bool
+
+	// Recursive unmounts all the descendants before unmounting the filesystem, it is ignored by UnmountAll
+	Recursive bool
+}
+
+func (o UnmountOptions) args() []string {
+	args := []string{"umount"}
+	if o.Force {
+		args = append(args, "-f")
+	}
+	return args
+}
+
+// MountAll mounts all available ZFS filesystems
+func MountAll(ctx context.Context, options MountOptions) error {
+	_, err := zfs(ctx, append(options.args(), "-a")...)
+	return err
+}
+
+// UnmountAll unmounts all mounted ZFS filesystems
+func UnmountAll(ctx context.Context, options UnmountOptions) error {
+	_, err := zfs(ctx, append(options.args(), "-a")...)
+	return err
+}
+
 // Mount mounts ZFS filesystem
 func (d *Filesystem) Mount(ctx context.Context) error {
-	_, err := zfs(ctx, "mount", d.Info.Name)
+	return d.MountWithOptions(ctx, MountOptions{})
+}
+
+// MountWithOptions mounts ZFS filesystem using options
+func (d *Filesystem) MountWithOptions(ctx context.Context, options MountOptions) error {
+	if options.KeyProvider != nil {
+		if err := loadKeyIfNeeded(ctx, d.Info.Name, options.KeyProvider); err != nil {
+			return err
+		}
+	}
+	_, err := zfs(ctx, append(options.args(), d.Info.Name)...)
 	return err
 }
 
 // Unmount unmounts ZFS filesystem
 func (d *Filesystem) Unmount(ctx context.Context) error {
-	_, err := zfs(ctx, "umount", d.Info.Name)
-	return err
+	return d.UnmountWithOptions(ctx, UnmountOptions{})
 }
 
-// LoadKey loads encryption key for dataset
+// UnmountWithOptions unmounts ZFS filesystem using options
+func (d *Filesystem) UnmountWithOptions(ctx context.Context, options UnmountOptions) error {
+	names := []string{d.Info.Name}
+	if options.Recursive {
+		out, err := zfs(ctx, "list", "-H", "-t", datasetFilesystem, "-o", "name,mounted", "-r", d.Info.Name)
+		if err != nil {
+			return err
+		}
+		names = names[:0]
+		for i := len(out) - 1; i >= 0; i-- {
+			if out[i][1] == "yes" {
+				names = append(names, out[i][0])
+			}
+		}
+	}
+	for _, name := range names {
+		if _, err := zfs(ctx, append(options.args(), name)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsMounted returns true if ZFS filesystem is mounted
+func (d *Filesystem) IsMounted(ctx context.Context) (bool, error) {
+	mounted, _, err := getProperty(ctx, d.Info.Name, "mounted")
+	if err != nil {
+		return false, err
+	}
+	return mounted == "yes", nil
+}
+
+// LoadKey loads encryption key for dataset.
+// It is a shorthand for loading the key provided by StaticKeyProvider, use LoadKeyFromProvider
+// to fetch the key of the encryption root from other sources.
 func (d *Filesystem) LoadKey(ctx context.Context, password string) error {
-	_, err := zfsStdin(ctx, bytes.NewReader([]byte(password)), "load-key", d.Info.Name)
-	return err
+	return loadProviderKey(ctx, d.Info.Name, StaticKeyProvider{Default: []byte(password)})
+}
+
+// LoadKeyFromProvider loads encryption key for the encryption root of the dataset using the key provider
+func (d *Filesystem) LoadKeyFromProvider(ctx context.Context, provider KeyProvider) error {
+	return loadKey(ctx, d.Info.Name, provider)
 }
 
 // UnloadKey unloads encryption key for dataset
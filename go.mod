@@ -6,16 +6,16 @@ require (
 	github.com/outofforest/libexec v0.3.9
 	github.com/outofforest/logger v0.4.0
 	github.com/outofforest/parallel v0.2.3
+	github.com/pkg/errors v0.9.1
 	github.com/stretchr/testify v1.8.3
+	go.uber.org/zap v1.25.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/ridge/must v0.6.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
-	go.uber.org/zap v1.25.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
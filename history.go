@@ -0,0 +1,135 @@
+package zfs
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const historyTimeLayout = "2006-01-02.15:04:05"
+
+var (
+	historyLongRegExp     = regexp.MustCompile(`^(.*?) \[user (\d+) (?:\(([^)]*)\) )?(?:on ([^:\]]*)(?::([^\]]*))?)?\]$`)
+	historyInternalRegExp = regexp.MustCompile(`^\[(?:internal (\S+) )?txg:(\d+)\] ?(.*)$`)
+	historyDatasetRegExp  = regexp.MustCompile(`^(\S+) \((\d+)\) ?(.*)$`)
+)
+
+// HistoryRecord is the record of ZPool command history
+type HistoryRecord struct {
+	Time time.Time
+
+	// Command is the command executed by the user, it is empty for internal records
+	Command string
+
+	// Internal is true for records of internal events and ioctls
+	Internal bool
+
+	// Event is the name of internal event or ioctl, e.g. create, destroy or snapshot
+	Event string
+
+	// TXG is the transaction group of the internal event
+	TXG uint64
+
+	// Dataset and DatasetID identify the dataset affected by the internal event
+	Dataset   string
+	DatasetID uint64
+
+	// Message is the rest of the internal event description
+	Message string
+
+	// UID, User, Host and Zone are reported only if long format is requested
+	UID  uint64
+	User string
+	Host string
+	Zone string
+}
+
+// History returns the command history of ZPool. If internal is set, internal events are included,
+// if long is set, user and host executing the command are reported.
+func (p *Pool) History(ctx context.Context, internal, long bool) ([]HistoryRecord, error) {
+	args := []string{"history"}
+	if internal {
+		args = append(args, "-i")
+	}
+	if long {
+		args = append(args, "-l")
+	}
+	out, err := zpoolOutput(ctx, append(args, p.Name)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseHistory(string(out))
+}
+
+func parseHistory(out string) ([]HistoryRecord, error) {
+	records := []HistoryRecord{}
+	for _, line := range strings.Split(out, "\n") {
+		// lines starting with space belong to nvlists dumped for ioctls
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "History for ") ||
+			line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		record, err := parseHistoryRecord(line)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func parseHistoryRecord(line string) (HistoryRecord, error) {
+	timestamp, text, ok := strings.Cut(line, " ")
+	if !ok {
+		return HistoryRecord{}, errors.Errorf("invalid history record: %q", line)
+	}
+	t, err := time.ParseInLocation(historyTimeLayout, timestamp, time.Local)
+	if err != nil {
+		return HistoryRecord{}, errors.Wrapf(err, "invalid history record: %q", line)
+	}
+	record := HistoryRecord{Time: t}
+
+	if match := historyLongRegExp.FindStringSubmatch(text); match != nil {
+		text = match[1]
+		if err := setUint(&record.UID, match[2]); err != nil {
+			return HistoryRecord{}, errors.Wrapf(err, "invalid history record: %q", line)
+		}
+		record.User = match[3]
+		record.Host = match[4]
+		record.Zone = match[5]
+	}
+
+	switch {
+	case strings.HasPrefix(text, "ioctl "):
+		record.Internal = true
+		record.Event = strings.TrimPrefix(text, "ioctl ")
+	case strings.HasPrefix(text, "["):
+		match := historyInternalRegExp.FindStringSubmatch(text)
+		if match == nil {
+			return HistoryRecord{}, errors.Errorf("invalid history record: %q", line)
+		}
+		record.Internal = true
+		if err := setUint(&record.TXG, match[2]); err != nil {
+			return HistoryRecord{}, errors.Wrapf(err, "invalid history record: %q", line)
+		}
+		record.Event = match[1]
+		text = match[3]
+		if record.Event == "" {
+			record.Event, text, _ = strings.Cut(text, " ")
+		}
+		if match := historyDatasetRegExp.FindStringSubmatch(text); match != nil {
+			record.Dataset = match[1]
+			if err := setUint(&record.DatasetID, match[2]); err != nil {
+				return HistoryRecord{}, errors.Wrapf(err, "invalid history record: %q", line)
+			}
+			text = match[3]
+		}
+		record.Message = strings.TrimSpace(text)
+	default:
+		record.Command = text
+	}
+	return record, nil
+}
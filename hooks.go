@@ -0,0 +1,129 @@
+package zfs
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/outofforest/logger"
+	"go.uber.org/zap"
+)
+
+type hooksKey struct{}
+
+// CommandInfo describes the invocation of zfs or zpool command
+type CommandInfo struct {
+	// Command is either zfs or zpool
+	Command string
+	Args    []string
+
+	// Stdin reports if data were passed to the standard input of the command. The data itself is never exposed
+	// because it may contain encryption keys and passwords.
+	Stdin bool
+
+	Start time.Time
+
+	// Duration, ExitCode, Stderr and Err are set after command exits, ExitCode is -1 if command wasn't started
+	// or was killed
+	Duration time.Duration
+	ExitCode int
+	Stderr   string
+	Err      error
+}
+
+// Hook is notified about each invocation of zfs and zpool commands
+type Hook interface {
+	// Before is called before command is started, returned context is passed to After
+	Before(ctx context.Context, info *CommandInfo) context.Context
+
+	// After is called after command exits
+	After(ctx context.Context, info *CommandInfo)
+}
+
+// WithHooks returns context with hooks added to the ones already stored in ctx.
+// Hooks are notified about all the commands executed using returned context.
+func WithHooks(ctx context.Context, hooks ...Hook) context.Context {
+	existing := hooksFromContext(ctx)
+	all := make([]Hook, 0, len(existing)+len(hooks))
+	all = append(append(all, existing...), hooks...)
+	return context.WithValue(ctx, hooksKey{}, all)
+}
+
+func hooksFromContext(ctx context.Context) []Hook {
+	hooks, _ := ctx.Value(hooksKey{}).([]Hook)
+	return hooks
+}
+
+// LogHook logs commands using logger stored in context. Successful commands are logged at debug level,
+// failed ones at warning level.
+type LogHook struct{}
+
+// Before does nothing
+func (LogHook) Before(ctx context.Context, info *CommandInfo) context.Context {
+	return ctx
+}
+
+// After logs the command
+func (LogHook) After(ctx context.Context, info *CommandInfo) {
+	fields := []zap.Field{
+		zap.String("command", info.Command),
+		zap.Strings("args", info.Args),
+		zap.Bool("stdin", info.Stdin),
+		zap.Duration("duration", info.Duration),
+		zap.Int("exitCode", info.ExitCode),
+	}
+	if info.Err != nil {
+		logger.Get(ctx).Warn("Command failed", append(fields, zap.String("stderr", info.Stderr))...)
+		return
+	}
+	logger.Get(ctx).Debug("Command executed", fields...)
+}
+
+// Tracer starts spans, it is implemented easily on top of OpenTelemetry tracer
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is the span of trace
+type Span interface {
+	SetAttribute(key, value string)
+	RecordError(err error)
+	End()
+}
+
+type spanKey struct {
+	hook *TraceHook
+}
+
+// TraceHook reports each command as a span
+type TraceHook struct {
+	Tracer Tracer
+}
+
+// Before starts the span
+func (h *TraceHook) Before(ctx context.Context, info *CommandInfo) context.Context {
+	name := info.Command
+	if len(info.Args) > 0 {
+		name += " " + info.Args[0]
+	}
+	ctx, span := h.Tracer.Start(ctx, name)
+	span.SetAttribute("command", info.Command)
+	span.SetAttribute("args", strings.Join(info.Args, " "))
+	span.SetAttribute("stdin", strconv.FormatBool(info.Stdin))
+	return context.WithValue(ctx, spanKey{hook: h}, span)
+}
+
+// After ends the span
+func (h *TraceHook) After(ctx context.Context, info *CommandInfo) {
+	span, ok := ctx.Value(spanKey{hook: h}).(Span)
+	if !ok {
+		return
+	}
+	span.SetAttribute("exitCode", strconv.Itoa(info.ExitCode))
+	if info.Err != nil {
+		span.SetAttribute("stderr", info.Stderr)
+		span.RecordError(info.Err)
+	}
+	span.End()
+}
@@ -0,0 +1,382 @@
+package zfs
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	ioStatBasicColumns = 7
+
+	// older versions of ZFS don't report rebuild columns
+	ioStatLatencyColumns    = 10
+	ioStatLatencyColumnsMax = 11
+	ioStatQueueColumns      = 12
+	ioStatQueueColumnsMax   = 14
+)
+
+// IOStatOptions stores options passed to IOStat method
+type IOStatOptions struct {
+	// Verbose requests statistics of each vdev
+	Verbose bool
+
+	// Latency requests average latencies
+	Latency bool
+
+	// Queues requests depths of I/O queues
+	Queues bool
+
+	// Histograms requests latency histograms, it can't be combined with Latency and Queues
+	Histograms bool
+
+	// SkipSinceBoot skips the first sample containing statistics since the pool was imported
+	SkipSinceBoot bool
+}
+
+// IOStatSample is the sample of I/O statistics reported by ZPool
+type IOStatSample struct {
+	Time  time.Time
+	Pool  IOStat
+	Vdevs []IOStat
+}
+
+// IOStat contains I/O statistics of the pool or vdev
+type IOStat struct {
+	Name string
+
+	Allocated  uint64
+	Free       uint64
+	ReadOps    uint64
+	WriteOps   uint64
+	ReadBytes  uint64
+	WriteBytes uint64
+
+	// Latency is set if IOStatOptions.Latency is set
+	Latency *IOLatency
+
+	// Queues is set if IOStatOptions.Queues is set
+	Queues *IOQueues
+
+	// Histogram is set if IOStatOptions.Histograms is set, other statistics are not reported then
+	Histogram []IOHistogramBucket
+}
+
+// IOLatency contains average latencies of I/O operations
+type IOLatency struct {
+	TotalRead       time.Duration
+	TotalWrite      time.Duration
+	DiskRead        time.Duration
+	DiskWrite       time.Duration
+	SyncQueueRead   time.Duration
+	SyncQueueWrite  time.Duration
+	AsyncQueueRead  time.Duration
+	AsyncQueueWrite time.Duration
+	Scrub           time.Duration
+	Trim            time.Duration
+	Rebuild         time.Duration
+}
+
+// IOQueue contains depth of I/O queue
+type IOQueue struct {
+	Pending uint64
+	Active  uint64
+}
+
+// IOQueues contains depths of I/O queues
+type IOQueues struct {
+	SyncRead     IOQueue
+	SyncWrite    IOQueue
+	AsyncRead    IOQueue
+	AsyncWrite   IOQueue
+	ScrubRead    IOQueue
+	TrimWrite    IOQueue
+	RebuildWrite IOQueue
+}
+
+// IOHistogramBucket contains numbers of I/O operations with latency falling into the bucket
+type IOHistogramBucket struct {
+	// Latency is the upper bound of the bucket
+	Latency time.Duration
+
+	TotalRead       uint64
+	TotalWrite      uint64
+	DiskRead        uint64
+	DiskWrite       uint64
+	SyncQueueRead   uint64
+	SyncQueueWrite  uint64
+	AsyncQueueRead  uint64
+	AsyncQueueWrite uint64
+	Scrub           uint64
+	Trim            uint64
+	Rebuild         uint64
+}
+
+// IOStat streams I/O statistics of ZPool sampled at the interval to the channel until context is canceled.
+// Channel is closed when function returns.
+func (p *Pool) IOStat(ctx context.Context, interval time.Duration, options IOStatOptions,
+	samples chan<- IOStatSample,
+) error {
+	defer close(samples)
+
+	if interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+	if options.Histograms && (options.Latency || options.Queues) {
+		return errors.New("histograms can't be combined with latency and queues")
+	}
+
+	args := []string{"iostat", "-H", "-p", "-T", "u"}
+	if options.Verbose {
+		args = append(args, "-v")
+	}
+	if options.Latency {
+		args = append(args, "-l")
+	}
+	if options.Queues {
+		args = append(args, "-q")
+	}
+	if options.Histograms {
+		args = append(args, "-w")
+	}
+	if options.SkipSinceBoot {
+		args = append(args, "-y")
+	}
+	args = append(args, p.Name, strconv.FormatFloat(interval.Seconds(), 'f', -1, 64))
+
+	parser := &ioStatParser{options: options}
+	send := func(sample *IOStatSample) error {
+		if sample == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.WithStack(ctx.Err())
+		case samples <- *sample:
+			return nil
+		}
+	}
+	return streamLines(ctx, func(ctx context.Context, stdout io.Writer) error {
+		return zpoolStdout(ctx, stdout, args...)
+	}, func(line []string) error {
+		sample, err := parser.parse(line)
+		if err != nil {
+			return err
+		}
+		return send(sample)
+	})
+}
+
+// ioStatParser parses the scripted output of zpool iostat
+type ioStatParser struct {
+	options IOStatOptions
+	sample  *IOStatSample
+	hasPool bool
+}
+
+// parse consumes the line of output and returns the sample once it is complete
+func (p *ioStatParser) parse(line []string) (*IOStatSample, error) {
+	fields := make([]string, 0, len(line))
+	for _, f := range line {
+		fields = append(fields, strings.TrimSpace(f))
+	}
+	for len(fields) > 0 && fields[len(fields)-1] == "" {
+		fields = fields[:len(fields)-1]
+	}
+
+	switch {
+	case len(fields) == 0:
+		return p.flush(), nil
+	case len(fields) == 1 && isDigits(fields[0]):
+		timestamp, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		sample := p.flush()
+		p.sample = &IOStatSample{Time: time.Unix(timestamp, 0)}
+		return sample, nil
+	case p.sample == nil:
+		return nil, errors.Errorf("unexpected line in iostat: %q", strings.Join(line, "\t"))
+	case p.options.Histograms:
+		return nil, p.parseHistogram(fields)
+	case len(fields) == 1:
+		// class of vdevs, like logs or cache
+		return nil, nil
+	}
+
+	stat, err := parseIOStat(fields)
+	if err != nil {
+		return nil, err
+	}
+	if stat == nil {
+		return nil, nil
+	}
+	p.add(*stat)
+	if !p.options.Verbose {
+		return p.flush(), nil
+	}
+	return nil, nil
+}
+
+func (p *ioStatParser) parseHistogram(fields []string) error {
+	if len(fields) == 1 {
+		p.add(IOStat{Name: fields[0], Histogram: []IOHistogramBucket{}})
+		return nil
+	}
+	if !p.hasPool {
+		return errors.Errorf("histogram bucket without vdev: %q", strings.Join(fields, "\t"))
+	}
+
+	values, err := parseIOStatValues(fields)
+	if err != nil {
+		return err
+	}
+	var bucket IOHistogramBucket
+	bucket.Latency = time.Duration(values[0])
+	if err := assignIOStatColumns(values[1:], &bucket.TotalRead, &bucket.TotalWrite, &bucket.DiskRead,
+		&bucket.DiskWrite, &bucket.SyncQueueRead, &bucket.SyncQueueWrite, &bucket.AsyncQueueRead,
+		&bucket.AsyncQueueWrite, &bucket.Scrub, &bucket.Trim, &bucket.Rebuild); err != nil {
+		return err
+	}
+
+	stat := &p.sample.Pool
+	if len(p.sample.Vdevs) > 0 {
+		stat = &p.sample.Vdevs[len(p.sample.Vdevs)-1]
+	}
+	stat.Histogram = append(stat.Histogram, bucket)
+	return nil
+}
+
+func (p *ioStatParser) add(stat IOStat) {
+	if !p.hasPool {
+		p.sample.Pool = stat
+		p.hasPool = true
+		return
+	}
+	p.sample.Vdevs = append(p.sample.Vdevs, stat)
+}
+
+func (p *ioStatParser) flush() *IOStatSample {
+	if p.sample == nil || !p.hasPool {
+		return nil
+	}
+	sample := p.sample
+	p.sample = nil
+	p.hasPool = false
+	return sample
+}
+
+func parseIOStat(fields []string) (*IOStat, error) {
+	if len(fields) < ioStatBasicColumns {
+		return nil, errors.Errorf("invalid iostat line: %q", strings.Join(fields, "\t"))
+	}
+
+	empty := true
+	for _, f := range fields[1:] {
+		if f != "-" {
+			empty = false
+			break
+		}
+	}
+	if empty {
+		// class of vdevs, like logs or cache
+		return nil, nil
+	}
+
+	values, err := parseIOStatValues(fields[1:])
+	if err != nil {
+		return nil, err
+	}
+	stat := &IOStat{
+		Name:       fields[0],
+		Allocated:  values[0],
+		Free:       values[1],
+		ReadOps:    values[2],
+		WriteOps:   values[3],
+		ReadBytes:  values[4],
+		WriteBytes: values[5],
+	}
+
+	extra := values[ioStatBasicColumns-1:]
+	var latency, queues []uint64
+	switch len(extra) {
+	case 0:
+	case ioStatLatencyColumns, ioStatLatencyColumnsMax:
+		latency = extra
+	case ioStatQueueColumns, ioStatQueueColumnsMax:
+		queues = extra
+	case ioStatLatencyColumns + ioStatQueueColumns:
+		latency, queues = extra[:ioStatLatencyColumns], extra[ioStatLatencyColumns:]
+	case ioStatLatencyColumnsMax + ioStatQueueColumnsMax:
+		latency, queues = extra[:ioStatLatencyColumnsMax], extra[ioStatLatencyColumnsMax:]
+	default:
+		return nil, errors.Errorf("unexpected number of iostat columns: %q", strings.Join(fields, "\t"))
+	}
+
+	if latency != nil {
+		var l [ioStatLatencyColumnsMax]uint64
+		copy(l[:], latency)
+		stat.Latency = &IOLatency{
+			TotalRead:       time.Duration(l[0]),
+			TotalWrite:      time.Duration(l[1]),
+			DiskRead:        time.Duration(l[2]),
+			DiskWrite:       time.Duration(l[3]),
+			SyncQueueRead:   time.Duration(l[4]),
+			SyncQueueWrite:  time.Duration(l[5]),
+			AsyncQueueRead:  time.Duration(l[6]),
+			AsyncQueueWrite: time.Duration(l[7]),
+			Scrub:           time.Duration(l[8]),
+			Trim:            time.Duration(l[9]),
+			Rebuild:         time.Duration(l[10]),
+		}
+	}
+	if queues != nil {
+		stat.Queues = &IOQueues{}
+		if err := assignIOStatColumns(queues, &stat.Queues.SyncRead.Pending, &stat.Queues.SyncRead.Active,
+			&stat.Queues.SyncWrite.Pending, &stat.Queues.SyncWrite.Active,
+			&stat.Queues.AsyncRead.Pending, &stat.Queues.AsyncRead.Active,
+			&stat.Queues.AsyncWrite.Pending, &stat.Queues.AsyncWrite.Active,
+			&stat.Queues.ScrubRead.Pending, &stat.Queues.ScrubRead.Active,
+			&stat.Queues.TrimWrite.Pending, &stat.Queues.TrimWrite.Active,
+			&stat.Queues.RebuildWrite.Pending, &stat.Queues.RebuildWrite.Active); err != nil {
+			return nil, err
+		}
+	}
+	return stat, nil
+}
+
+func parseIOStatValues(fields []string) ([]uint64, error) {
+	values := make([]uint64, 0, len(fields))
+	for _, f := range fields {
+		var v uint64
+		if err := setUint(&v, f); err != nil {
+			return nil, errors.Wrapf(err, "invalid iostat value %q", f)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// assignIOStatColumns assigns values to targets, trailing targets may be missing in the output of older versions
+func assignIOStatColumns(values []uint64, targets ...*uint64) error {
+	if len(values) > len(targets) {
+		return errors.Errorf("unexpected number of iostat columns: %d", len(values))
+	}
+	for i, v := range values {
+		*targets[i] = v
+	}
+	return nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
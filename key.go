@@ -0,0 +1,228 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// KeyFormat is the format of the encryption key
+type KeyFormat string
+
+// Key formats
+const (
+	KeyFormatRaw        KeyFormat = "raw"
+	KeyFormatHex        KeyFormat = "hex"
+	KeyFormatPassphrase KeyFormat = "passphrase"
+)
+
+// KeyLocationPrompt is the key location instructing zfs to read the key from standard input
+const KeyLocationPrompt = "prompt"
+
+// KeyStatus is the status of the encryption key of a dataset
+type KeyStatus string
+
+// Key statuses
+const (
+	KeyStatusNone        KeyStatus = ""
+	KeyStatusAvailable   KeyStatus = "available"
+	KeyStatusUnavailable KeyStatus = "unavailable"
+)
+
+// KeyOptions stores encryption key options of the dataset
+type KeyOptions struct {
+	// Encryption is the encryption algorithm, e.g. aes-256-gcm, "on" is used if empty
+	Encryption string
+
+	// Format is the format of the key, passphrase is used if empty
+	Format KeyFormat
+
+	// Location is the location of the key, either prompt or file:///path, prompt is used if empty
+	Location string
+
+	// Key is the key material passed to zfs if location is prompt and Provider is nil
+	Key []byte
+
+	// Provider provides the key material passed to zfs if location is prompt, it takes precedence over Key
+	Provider KeyProvider
+
+	// PBKDF2Iters is the number of PBKDF2 iterations used to derive key from passphrase, default is used if 0
+	PBKDF2Iters uint64
+}
+
+func (o KeyOptions) format() KeyFormat {
+	if o.Format == "" {
+		return KeyFormatPassphrase
+	}
+	return o.Format
+}
+
+func (o KeyOptions) location() string {
+	if o.Location == "" {
+		return KeyLocationPrompt
+	}
+	return o.Location
+}
+
+// args returns options describing the key, encryption algorithm is included only if requested
+func (o KeyOptions) args(withEncryption bool) []string {
+	args := make([]string, 0, 8)
+	if withEncryption {
+		encryption := o.Encryption
+		if encryption == "" {
+			encryption = "on"
+		}
+		args = append(args, "-o", "encryption="+encryption)
+	}
+	args = append(args, "-o", "keyformat="+string(o.format()), "-o", "keylocation="+o.location())
+	if o.PBKDF2Iters > 0 {
+		args = append(args, "-o", "pbkdf2iters="+strconv.FormatUint(o.PBKDF2Iters, 10))
+	}
+	return args
+}
+
+// stdin returns the input providing new key of the encryption root to zfs
+func (o KeyOptions) stdin(ctx context.Context, encryptionRoot string) (io.Reader, error) {
+	if o.location() != KeyLocationPrompt {
+		return nil, nil
+	}
+	var provider KeyProvider = StaticKeyProvider{Default: o.Key}
+	if o.Provider != nil {
+		provider = o.Provider
+	}
+	key, err := provider.Key(ctx, encryptionRoot)
+	if err != nil {
+		return nil, err
+	}
+	return newKeyInput(o.format(), key), nil
+}
+
+// newKeyInput returns the input for commands asking for the new key to be confirmed
+func newKeyInput(format KeyFormat, key []byte) io.Reader {
+	if format == KeyFormatRaw {
+		return bytes.NewReader(key)
+	}
+	input := make([]byte, 0, 2*len(key)+1)
+	input = append(input, key...)
+	input = append(input, '\n')
+	input = append(input, key...)
+	return bytes.NewReader(input)
+}
+
+// ChangeKeyOptions stores options passed to ChangeKey method
+type ChangeKeyOptions struct {
+	// Inherit makes the dataset inherit the key from its parent
+	Inherit bool
+
+	// Key describes the new key, ignored if Inherit is set
+	Key KeyOptions
+}
+
+// ChangeKey changes the encryption key of the filesystem.
+// The key of the filesystem must be loaded.
+func (d *Filesystem) ChangeKey(ctx context.Context, options ChangeKeyOptions) error {
+	if options.Inherit {
+		_, err := zfs(ctx, "change-key", "-i", d.Info.Name)
+		return err
+	}
+
+	stdin, err := options.Key.stdin(ctx, d.Info.Name)
+	if err != nil {
+		return err
+	}
+	args := append([]string{"change-key"}, options.Key.args(false)...)
+	args = append(args, d.Info.Name)
+	_, err = zfsStdin(ctx, stdin, args...)
+	return err
+}
+
+// KeyStatus returns the status of the encryption key of the filesystem.
+// KeyStatusNone is returned if filesystem is not encrypted.
+func (d *Filesystem) KeyStatus(ctx context.Context) (KeyStatus, error) {
+	status, exists, err := getProperty(ctx, d.Info.Name, "keystatus")
+	if err != nil || !exists {
+		return KeyStatusNone, err
+	}
+	return KeyStatus(status), nil
+}
+
+// LoadKeysOptions stores options passed to LoadKeys function
+type LoadKeysOptions struct {
+	// Recursive causes keys of all the descendants of the dataset to be loaded
+	Recursive bool
+
+	// Mount causes filesystems to be mounted after loading keys
+	Mount bool
+}
+
+// LoadKeyResult is the outcome of LoadKeys for single dataset
+type LoadKeyResult struct {
+	Dataset        string
+	EncryptionRoot string
+
+	// KeyLoaded is true if key of the dataset has been loaded by the call
+	KeyLoaded bool
+
+	// Mounted is true if filesystem has been mounted by the call
+	Mounted bool
+
+	Err error
+}
+
+// LoadKeys loads keys of the encryption roots of the dataset and, optionally, its descendants.
+// Keys stored at the prompt location are fetched from the provider, other ones are loaded from their key locations.
+// Error is returned only if datasets can't be discovered, failures of loading and mounting are reported per dataset.
+func LoadKeys(ctx context.Context, name string, provider KeyProvider, options LoadKeysOptions) ([]LoadKeyResult, error) {
+	args := []string{"list", "-H", "-t", "filesystem,volume", "-o",
+		"name,type,encryptionroot,keystatus,canmount,mountpoint,mounted"}
+	if options.Recursive {
+		args = append(args, "-r")
+	}
+	out, err := zfs(ctx, append(args, name)...)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]LoadKeyResult, 0, len(out))
+	rootErrs := map[string]error{}
+	for _, line := range out {
+		result := LoadKeyResult{Dataset: line[0]}
+		setString(&result.EncryptionRoot, line[2])
+		if result.EncryptionRoot != "" && KeyStatus(line[3]) == KeyStatusUnavailable {
+			rootErr, loaded := rootErrs[result.EncryptionRoot]
+			if !loaded {
+				rootErr = loadRootKey(ctx, result.EncryptionRoot, provider)
+				rootErrs[result.EncryptionRoot] = rootErr
+			}
+			result.KeyLoaded = rootErr == nil
+			result.Err = rootErr
+		}
+
+		if result.Err == nil && options.Mount && line[1] == datasetFilesystem && line[4] == "on" &&
+			line[5] != "none" && line[5] != "legacy" && line[6] != "yes" {
+			if _, result.Err = zfs(ctx, "mount", result.Dataset); result.Err == nil {
+				result.Mounted = true
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func loadRootKey(ctx context.Context, root string, provider KeyProvider) error {
+	location, _, err := getProperty(ctx, root, "keylocation")
+	if err != nil {
+		return err
+	}
+	if location != KeyLocationPrompt {
+		_, err := zfs(ctx, "load-key", root)
+		return err
+	}
+	if provider == nil {
+		return errors.Errorf("key provider is required to load key of %q", root)
+	}
+	return loadProviderKey(ctx, root, provider)
+}
@@ -0,0 +1,143 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// KeyProvider provides encryption keys for datasets.
+// Key is called with the name of the encryption root whenever the package needs its key.
+type KeyProvider interface {
+	Key(ctx context.Context, encryptionRoot string) ([]byte, error)
+}
+
+// StaticKeyProvider provides keys stored in memory
+type StaticKeyProvider struct {
+	// Keys maps encryption roots to their keys
+	Keys map[string][]byte
+
+	// Default is the key returned for encryption roots not present in Keys
+	Default []byte
+}
+
+// Key returns the key for encryption root
+func (p StaticKeyProvider) Key(ctx context.Context, encryptionRoot string) ([]byte, error) {
+	if key, exists := p.Keys[encryptionRoot]; exists {
+		return key, nil
+	}
+	if p.Default != nil {
+		return p.Default, nil
+	}
+	return nil, errors.Errorf("no key for encryption root %q", encryptionRoot)
+}
+
+// FileKeyProvider provides keys stored in files.
+// Key of each encryption root is stored in the file named after the path-escaped name of the root,
+// e.g. key of pool/fs is stored in pool%2Ffs.
+type FileKeyProvider struct {
+	Dir string
+}
+
+// Key returns the key for encryption root
+func (p FileKeyProvider) Key(ctx context.Context, encryptionRoot string) ([]byte, error) {
+	key, err := os.ReadFile(filepath.Join(p.Dir, url.PathEscape(encryptionRoot)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading key for encryption root %q failed", encryptionRoot)
+	}
+	return key, nil
+}
+
+// EnvKeyProvider provides keys stored in environment variables.
+// Key of each encryption root is stored in the variable named after the prefix followed by the name of the root
+// converted to upper case, with all the characters other than letters and digits replaced by underscores,
+// e.g. key of pool/fs is stored in <Prefix>POOL_FS.
+type EnvKeyProvider struct {
+	Prefix string
+}
+
+// Key returns the key for encryption root
+func (p EnvKeyProvider) Key(ctx context.Context, encryptionRoot string) ([]byte, error) {
+	name := p.Prefix + strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII || (!unicode.IsLetter(r) && !unicode.IsDigit(r)) {
+			return '_'
+		}
+		return unicode.ToUpper(r)
+	}, encryptionRoot)
+
+	key, exists := os.LookupEnv(name)
+	if !exists {
+		return nil, errors.Errorf("no key for encryption root %q in variable %s", encryptionRoot, name)
+	}
+	return []byte(key), nil
+}
+
+// LocalKMS is a stand-in for a key management service deriving keys from the master key.
+// Keys are returned as hex strings, so datasets should use KeyFormatHex.
+type LocalKMS struct {
+	MasterKey []byte
+}
+
+// Key returns the key for encryption root
+func (p LocalKMS) Key(ctx context.Context, encryptionRoot string) ([]byte, error) {
+	if len(p.MasterKey) == 0 {
+		return nil, errors.New("master key is not set")
+	}
+
+	mac := hmac.New(sha256.New, p.MasterKey)
+	mac.Write([]byte(encryptionRoot))
+	sum := mac.Sum(nil)
+
+	key := make([]byte, hex.EncodedLen(len(sum)))
+	hex.Encode(key, sum)
+	return key, nil
+}
+
+func encryptionRoot(ctx context.Context, name string) (string, error) {
+	root, exists, err := getProperty(ctx, name, "encryptionroot")
+	if err != nil {
+		return "", err
+	}
+	if !exists || root == "" {
+		return "", errors.Errorf("dataset %q is not encrypted", name)
+	}
+	return root, nil
+}
+
+// loadKey loads the key of the encryption root of the dataset using the provider
+func loadKey(ctx context.Context, name string, provider KeyProvider) error {
+	root, err := encryptionRoot(ctx, name)
+	if err != nil {
+		return err
+	}
+	return loadProviderKey(ctx, root, provider)
+}
+
+// loadProviderKey loads the key of the encryption root fetched from the provider.
+// Key is always read from standard input, even if the key location of the encryption root points to a file.
+func loadProviderKey(ctx context.Context, root string, provider KeyProvider) error {
+	key, err := provider.Key(ctx, root)
+	if err != nil {
+		return err
+	}
+	_, err = zfsStdin(ctx, bytes.NewReader(key), "load-key", "-L", KeyLocationPrompt, root)
+	return err
+}
+
+// loadKeyIfNeeded loads the key of the dataset using the provider if the key is not loaded yet
+func loadKeyIfNeeded(ctx context.Context, name string, provider KeyProvider) error {
+	status, exists, err := getProperty(ctx, name, "keystatus")
+	if err != nil || !exists || KeyStatus(status) != KeyStatusUnavailable {
+		return err
+	}
+	return loadKey(ctx, name, provider)
+}
@@ -0,0 +1,278 @@
+// Package kstat reads kernel statistics exported by ZFS in /proc/spl/kstat/zfs.
+package kstat
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultRoot is the directory where kernel exports statistics of ZFS
+const DefaultRoot = "/proc/spl/kstat/zfs"
+
+// Reader reads kernel statistics of ZFS
+type Reader struct {
+	root string
+}
+
+// NewReader returns reader of statistics stored in root directory, DefaultRoot is used if root is empty
+func NewReader(root string) *Reader {
+	if root == "" {
+		root = DefaultRoot
+	}
+	return &Reader{root: root}
+}
+
+// ARCStats returns statistics of ARC
+func (r *Reader) ARCStats() (ARCStats, error) {
+	var stats ARCStats
+	return stats, r.readNamed("arcstats", &stats)
+}
+
+// DMUTx returns statistics of DMU transactions
+func (r *Reader) DMUTx() (DMUTx, error) {
+	var stats DMUTx
+	return stats, r.readNamed("dmu_tx", &stats)
+}
+
+// ZIL returns statistics of ZFS intent log
+func (r *Reader) ZIL() (ZIL, error) {
+	var stats ZIL
+	return stats, r.readNamed("zil", &stats)
+}
+
+// ABDStats returns statistics of ARC buffer data
+func (r *Reader) ABDStats() (ABDStats, error) {
+	var stats ABDStats
+	return stats, r.readNamed("abdstats", &stats)
+}
+
+// Pools returns names of pools statistics are available for
+func (r *Reader) Pools() ([]string, error) {
+	entries, err := os.ReadDir(r.root)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	pools := []string{}
+	for _, e := range entries {
+		if e.IsDir() {
+			pools = append(pools, e.Name())
+		}
+	}
+	return pools, nil
+}
+
+// PoolIO returns I/O statistics of the pool.
+// Statistics are read from iostats exported by OpenZFS 2.1 and newer, modules not exporting them are expected
+// to export legacy io statistics.
+func (r *Reader) PoolIO(pool string) (PoolIO, error) {
+	file := filepath.Join(pool, "iostats")
+	data, err := r.readNamedData(file)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return r.legacyPoolIO(pool)
+	case err != nil:
+		return PoolIO{}, err
+	}
+
+	var stats PoolIO
+	var counters poolIOStats
+	if err := decode(data, &stats); err != nil {
+		return PoolIO{}, errors.Wrapf(err, "decoding statistics in %q failed", file)
+	}
+	if err := decode(data, &counters); err != nil {
+		return PoolIO{}, errors.Wrapf(err, "decoding statistics in %q failed", file)
+	}
+	stats.Reads = counters.ARCReads + counters.DirectReads
+	stats.ReadBytes = counters.ARCReadBytes + counters.DirectReadBytes
+	stats.Writes = counters.ARCWrites + counters.DirectWrites
+	stats.WrittenBytes = counters.ARCWrittenBytes + counters.DirectWrittenBytes
+	return stats, nil
+}
+
+// legacyPoolIO reads io statistics exported by modules older than OpenZFS 2.1
+func (r *Reader) legacyPoolIO(pool string) (PoolIO, error) {
+	lines, err := r.readLines(filepath.Join(pool, "io"))
+	if err != nil {
+		return PoolIO{}, err
+	}
+	if len(lines) < 3 {
+		return PoolIO{}, errors.Errorf("invalid io statistics of pool %q", pool)
+	}
+
+	names := strings.Fields(lines[1])
+	values := strings.Fields(lines[2])
+	if len(names) != len(values) {
+		return PoolIO{}, errors.Errorf("invalid io statistics of pool %q", pool)
+	}
+	data := make(map[string]string, len(names))
+	for i, name := range names {
+		data[name] = values[i]
+	}
+
+	var stats PoolIO
+	if err := decode(data, &stats); err != nil {
+		return PoolIO{}, errors.Wrapf(err, "decoding io statistics of pool %q failed", pool)
+	}
+	return stats, nil
+}
+
+// PoolTXGs returns statistics of the recent transaction groups of the pool
+func (r *Reader) PoolTXGs(pool string) ([]TXG, error) {
+	lines, err := r.readLines(filepath.Join(pool, "txgs"))
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, errors.Errorf("invalid txg statistics of pool %q", pool)
+	}
+
+	names := strings.Fields(lines[0])
+	txgs := make([]TXG, 0, len(lines)-1)
+	for _, line := range lines[1:] {
+		values := strings.Fields(line)
+		if len(values) == 0 {
+			continue
+		}
+		if len(values) != len(names) {
+			return nil, errors.Errorf("invalid txg statistics of pool %q: %q", pool, line)
+		}
+		data := make(map[string]string, len(names))
+		for i, name := range names {
+			data[name] = values[i]
+		}
+
+		var txg txgStats
+		if err := decode(data, &txg); err != nil {
+			return nil, errors.Wrapf(err, "decoding txg statistics of pool %q failed", pool)
+		}
+		txgs = append(txgs, TXG{
+			TXG:          txg.TXG,
+			Birth:        time.Duration(txg.Birth),
+			State:        TXGState(data["state"]),
+			Dirty:        txg.Dirty,
+			ReadBytes:    txg.ReadBytes,
+			WrittenBytes: txg.WrittenBytes,
+			Reads:        txg.Reads,
+			Writes:       txg.Writes,
+			OpenTime:     time.Duration(txg.OpenTime),
+			QuiesceTime:  time.Duration(txg.QuiesceTime),
+			WaitTime:     time.Duration(txg.WaitTime),
+			SyncTime:     time.Duration(txg.SyncTime),
+		})
+	}
+	return txgs, nil
+}
+
+// readNamed reads statistics stored in the format of named kstat:
+//
+//	13 1 0x01 147 39984 3989349925 1064493402879766
+//	name                            type data
+//	hits                            4    1295542
+func (r *Reader) readNamed(file string, v interface{}) error {
+	data, err := r.readNamedData(file)
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(decode(data, v), "decoding statistics in %q failed", file)
+}
+
+// readNamedData reads values of named kstat
+func (r *Reader) readNamedData(file string) (map[string]string, error) {
+	lines, err := r.readLines(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) < 2 {
+		return nil, errors.Errorf("invalid statistics in %q", file)
+	}
+
+	data := make(map[string]string, len(lines)-2)
+	for _, line := range lines[2:] {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		data[fields[0]] = fields[2]
+	}
+	return data, nil
+}
+
+func (r *Reader) readLines(file string) ([]string, error) {
+	content, err := os.ReadFile(filepath.Join(r.root, file))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return strings.Split(strings.TrimSpace(string(content)), "\n"), nil
+}
+
+// decode sets fields of struct pointed by v using values referenced by kstat tags
+func decode(data map[string]string, v interface{}) error {
+	value := reflect.ValueOf(v).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		name, _ := parseTag(value.Type().Field(i).Tag)
+		if name == "" {
+			continue
+		}
+		raw, exists := data[name]
+		if !exists {
+			continue
+		}
+
+		field := value.Field(i)
+		switch field.Kind() {
+		case reflect.Uint64:
+			v, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				return errors.Wrapf(err, "invalid value of %q", name)
+			}
+			field.SetUint(v)
+		case reflect.Int64:
+			v, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return errors.Wrapf(err, "invalid value of %q", name)
+			}
+			field.SetInt(v)
+		default:
+			return errors.Errorf("unsupported type of field %q", value.Type().Field(i).Name)
+		}
+	}
+	return nil
+}
+
+// Delta returns the difference between two samples of statistics. Counters are subtracted while gauges,
+// tagged with gauge option, keep the current value. Counters which were reset are reported as current value.
+func Delta[T any](current, previous T) T {
+	result := current
+	cur := reflect.ValueOf(current)
+	prev := reflect.ValueOf(previous)
+	res := reflect.ValueOf(&result).Elem()
+	for i := 0; i < cur.NumField(); i++ {
+		name, gauge := parseTag(cur.Type().Field(i).Tag)
+		if name == "" || gauge {
+			continue
+		}
+		switch cur.Field(i).Kind() {
+		case reflect.Uint64:
+			if c, p := cur.Field(i).Uint(), prev.Field(i).Uint(); c >= p {
+				res.Field(i).SetUint(c - p)
+			}
+		case reflect.Int64:
+			if c, p := cur.Field(i).Int(), prev.Field(i).Int(); c >= p {
+				res.Field(i).SetInt(c - p)
+			}
+		}
+	}
+	return result
+}
+
+func parseTag(tag reflect.StructTag) (string, bool) {
+	name, options, _ := strings.Cut(tag.Get("kstat"), ",")
+	return name, options == "gauge"
+}
@@ -0,0 +1,172 @@
+package kstat
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const arcStats = `13 1 0x01 147 39984 3989349925 1064493402879766
+name                            type data
+hits                            4    900
+misses                          4    100
+c                               4    1073741824
+size                            4    536870912
+memory_available_bytes          3    -1048576
+l2_hits                         4    0
+arc_raw_size                    4    0
+`
+
+const dmuTx = `4 1 0x01 13 3536 3989387645 1064493403331220
+name                            type data
+dmu_tx_assigned                 4    5000
+dmu_tx_delay                    4    2
+dmu_tx_dirty_throttle           4    7
+`
+
+const poolIO = `12 3 0x00 1 80 2225326830828 32364429087911
+nread    nwritten reads    writes   wtime    wlentime wupdate  rtime    rlentime rupdate  wcnt     rcnt
+1024     2048     10       20       100      200      300      400      500      600      1        2
+`
+
+const poolIOStatsText = `47 1 0x01 30 8160 3989427863 1064493403886371
+name                            type data
+trim_extents_written            4    3
+trim_bytes_written              4    4096
+trim_extents_skipped            4    0
+trim_bytes_skipped              4    0
+trim_extents_failed             4    0
+trim_bytes_failed               4    0
+autotrim_extents_written        4    5
+autotrim_bytes_written          4    8192
+autotrim_extents_skipped        4    0
+autotrim_bytes_skipped          4    0
+autotrim_extents_failed         4    0
+autotrim_bytes_failed           4    0
+simple_trim_extents_written     4    0
+simple_trim_bytes_written       4    0
+simple_trim_extents_skipped     4    0
+simple_trim_bytes_skipped       4    0
+simple_trim_extents_failed      4    0
+simple_trim_bytes_failed        4    0
+arc_read_count                  4    10
+arc_read_bytes                  4    1024
+arc_write_count                 4    20
+arc_write_bytes                 4    2048
+direct_read_count               4    1
+direct_read_bytes               4    512
+direct_write_count              4    2
+direct_write_bytes              4    256
+`
+
+const poolTXGs = `txg      birth            state ndirty       nread        nwritten     reads    writes   otime        qtime        wtime        stime
+5        4128447390       C     4096         0            8192         0        2        5000000000   3567         11766        48893
+6        9128447390       O     0            0            0            0        0        0            0            0            0
+`
+
+func writeStats(t *testing.T) string {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "legacy"), 0o700))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "tank"), 0o700))
+	for file, content := range map[string]string{
+		"arcstats":     arcStats,
+		"dmu_tx":       dmuTx,
+		"legacy/io":    poolIO,
+		"tank/iostats": poolIOStatsText,
+		"tank/txgs":    poolTXGs,
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(root, file), []byte(content), 0o600))
+	}
+	return root
+}
+
+func TestReader(t *testing.T) {
+	r := NewReader(writeStats(t))
+
+	arc, err := r.ARCStats()
+	require.NoError(t, err)
+	assert.EqualValues(t, 900, arc.Hits)
+	assert.EqualValues(t, 100, arc.Misses)
+	assert.EqualValues(t, 1073741824, arc.Target)
+	assert.EqualValues(t, 536870912, arc.Size)
+	assert.EqualValues(t, -1048576, arc.MemoryAvailable)
+	assert.Equal(t, 0.9, arc.HitRatio())
+	assert.Zero(t, arc.L2HitRatio())
+
+	tx, err := r.DMUTx()
+	require.NoError(t, err)
+	assert.Equal(t, DMUTx{Assigned: 5000, Delay: 2, DirtyThrottle: 7}, tx)
+
+	_, err = r.ZIL()
+	require.Error(t, err)
+
+	pools, err := r.Pools()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"legacy", "tank"}, pools)
+
+	io, err := r.PoolIO("tank")
+	require.NoError(t, err)
+	assert.Equal(t, PoolIO{
+		ReadBytes:              1536,
+		WrittenBytes:           2304,
+		Reads:                  11,
+		Writes:                 22,
+		TrimExtentsWritten:     3,
+		TrimBytesWritten:       4096,
+		AutotrimExtentsWritten: 5,
+		AutotrimBytesWritten:   8192,
+	}, io)
+
+	io, err = r.PoolIO("legacy")
+	require.NoError(t, err)
+	assert.Equal(t, PoolIO{
+		ReadBytes:      1024,
+		WrittenBytes:   2048,
+		Reads:          10,
+		Writes:         20,
+		WaitTime:       100,
+		WaitLengthTime: 200,
+		WaitUpdate:     300,
+		RunTime:        400,
+		RunLengthTime:  500,
+		RunUpdate:      600,
+		WaitCount:      1,
+		RunCount:       2,
+	}, io)
+
+	txgs, err := r.PoolTXGs("tank")
+	require.NoError(t, err)
+	require.Len(t, txgs, 2)
+	assert.Equal(t, TXG{
+		TXG:          5,
+		Birth:        4128447390,
+		State:        TXGCommitted,
+		Dirty:        4096,
+		WrittenBytes: 8192,
+		Writes:       2,
+		OpenTime:     5 * time.Second,
+		QuiesceTime:  3567,
+		WaitTime:     11766,
+		SyncTime:     48893,
+	}, txgs[0])
+	assert.Equal(t, TXGOpen, txgs[1].State)
+}
+
+func TestDelta(t *testing.T) {
+	previous := ARCStats{Hits: 100, Misses: 50, Size: 1000, MemoryAvailable: -10}
+	current := ARCStats{Hits: 150, Misses: 60, Size: 800, MemoryAvailable: 20}
+
+	delta := Delta(current, previous)
+	assert.EqualValues(t, 50, delta.Hits)
+	assert.EqualValues(t, 10, delta.Misses)
+	assert.EqualValues(t, 800, delta.Size)
+	assert.EqualValues(t, 20, delta.MemoryAvailable)
+
+	// counters reset
+	delta = Delta(ARCStats{Hits: 10}, previous)
+	assert.EqualValues(t, 10, delta.Hits)
+}
@@ -0,0 +1,209 @@
+package kstat
+
+import "time"
+
+// ARCStats contains statistics of ARC
+type ARCStats struct {
+	Hits                   uint64 `kstat:"hits"`
+	Misses                 uint64 `kstat:"misses"`
+	DemandDataHits         uint64 `kstat:"demand_data_hits"`
+	DemandDataMisses       uint64 `kstat:"demand_data_misses"`
+	DemandMetadataHits     uint64 `kstat:"demand_metadata_hits"`
+	DemandMetadataMisses   uint64 `kstat:"demand_metadata_misses"`
+	PrefetchDataHits       uint64 `kstat:"prefetch_data_hits"`
+	PrefetchDataMisses     uint64 `kstat:"prefetch_data_misses"`
+	PrefetchMetadataHits   uint64 `kstat:"prefetch_metadata_hits"`
+	PrefetchMetadataMisses uint64 `kstat:"prefetch_metadata_misses"`
+	MRUHits                uint64 `kstat:"mru_hits"`
+	MRUGhostHits           uint64 `kstat:"mru_ghost_hits"`
+	MFUHits                uint64 `kstat:"mfu_hits"`
+	MFUGhostHits           uint64 `kstat:"mfu_ghost_hits"`
+	Deleted                uint64 `kstat:"deleted"`
+	MutexMiss              uint64 `kstat:"mutex_miss"`
+	EvictSkip              uint64 `kstat:"evict_skip"`
+	MemoryThrottleCount    uint64 `kstat:"memory_throttle_count"`
+
+	Size         uint64 `kstat:"size,gauge"`
+	Target       uint64 `kstat:"c,gauge"`
+	TargetMin    uint64 `kstat:"c_min,gauge"`
+	TargetMax    uint64 `kstat:"c_max,gauge"`
+	DataSize     uint64 `kstat:"data_size,gauge"`
+	MetadataSize uint64 `kstat:"metadata_size,gauge"`
+	HeaderSize   uint64 `kstat:"hdr_size,gauge"`
+	DbufSize     uint64 `kstat:"dbuf_size,gauge"`
+	DnodeSize    uint64 `kstat:"dnode_size,gauge"`
+	BonusSize    uint64 `kstat:"bonus_size,gauge"`
+	AnonSize     uint64 `kstat:"anon_size,gauge"`
+	MRUSize      uint64 `kstat:"mru_size,gauge"`
+	MFUSize      uint64 `kstat:"mfu_size,gauge"`
+	MetaUsed     uint64 `kstat:"arc_meta_used,gauge"`
+	NoGrow       uint64 `kstat:"arc_no_grow,gauge"`
+
+	MemoryFree      int64 `kstat:"memory_free_bytes,gauge"`
+	MemoryAvailable int64 `kstat:"memory_available_bytes,gauge"`
+
+	L2Hits       uint64 `kstat:"l2_hits"`
+	L2Misses     uint64 `kstat:"l2_misses"`
+	L2ReadBytes  uint64 `kstat:"l2_read_bytes"`
+	L2WriteBytes uint64 `kstat:"l2_write_bytes"`
+	L2Size       uint64 `kstat:"l2_size,gauge"`
+	L2AllocSize  uint64 `kstat:"l2_asize,gauge"`
+}
+
+// HitRatio returns the ratio of ARC hits to all the accesses
+func (s ARCStats) HitRatio() float64 {
+	return ratio(s.Hits, s.Misses)
+}
+
+// L2HitRatio returns the ratio of L2ARC hits to all the accesses
+func (s ARCStats) L2HitRatio() float64 {
+	return ratio(s.L2Hits, s.L2Misses)
+}
+
+// DMUTx contains statistics of DMU transactions
+type DMUTx struct {
+	Assigned        uint64 `kstat:"dmu_tx_assigned"`
+	Delay           uint64 `kstat:"dmu_tx_delay"`
+	Error           uint64 `kstat:"dmu_tx_error"`
+	Suspended       uint64 `kstat:"dmu_tx_suspended"`
+	Group           uint64 `kstat:"dmu_tx_group"`
+	MemoryReserve   uint64 `kstat:"dmu_tx_memory_reserve"`
+	MemoryReclaim   uint64 `kstat:"dmu_tx_memory_reclaim"`
+	DirtyThrottle   uint64 `kstat:"dmu_tx_dirty_throttle"`
+	DirtyDelay      uint64 `kstat:"dmu_tx_dirty_delay"`
+	DirtyOverMax    uint64 `kstat:"dmu_tx_dirty_over_max"`
+	DirtyFreesDelay uint64 `kstat:"dmu_tx_dirty_frees_delay"`
+	WrlogOverMax    uint64 `kstat:"dmu_tx_wrlog_over_max"`
+	Quota           uint64 `kstat:"dmu_tx_quota"`
+}
+
+// ZIL contains statistics of ZFS intent log
+type ZIL struct {
+	CommitCount            uint64 `kstat:"zil_commit_count"`
+	CommitWriterCount      uint64 `kstat:"zil_commit_writer_count"`
+	ITXCount               uint64 `kstat:"zil_itx_count"`
+	ITXIndirectCount       uint64 `kstat:"zil_itx_indirect_count"`
+	ITXIndirectBytes       uint64 `kstat:"zil_itx_indirect_bytes"`
+	ITXCopiedCount         uint64 `kstat:"zil_itx_copied_count"`
+	ITXCopiedBytes         uint64 `kstat:"zil_itx_copied_bytes"`
+	ITXNeedCopyCount       uint64 `kstat:"zil_itx_needcopy_count"`
+	ITXNeedCopyBytes       uint64 `kstat:"zil_itx_needcopy_bytes"`
+	ITXMetaslabNormalCount uint64 `kstat:"zil_itx_metaslab_normal_count"`
+	ITXMetaslabNormalBytes uint64 `kstat:"zil_itx_metaslab_normal_bytes"`
+	ITXMetaslabSlogCount   uint64 `kstat:"zil_itx_metaslab_slog_count"`
+	ITXMetaslabSlogBytes   uint64 `kstat:"zil_itx_metaslab_slog_bytes"`
+}
+
+// ABDStats contains statistics of ARC buffer data
+type ABDStats struct {
+	StructSize        uint64 `kstat:"struct_size,gauge"`
+	LinearCount       uint64 `kstat:"linear_cnt,gauge"`
+	LinearDataSize    uint64 `kstat:"linear_data_size,gauge"`
+	ScatterCount      uint64 `kstat:"scatter_cnt,gauge"`
+	ScatterDataSize   uint64 `kstat:"scatter_data_size,gauge"`
+	ScatterChunkWaste uint64 `kstat:"scatter_chunk_waste,gauge"`
+
+	ScatterPageMultiChunk uint64 `kstat:"scatter_page_multi_chunk"`
+	ScatterPageMultiZone  uint64 `kstat:"scatter_page_multi_zone"`
+	ScatterPageAllocRetry uint64 `kstat:"scatter_page_alloc_retry"`
+	ScatterSGTableRetry   uint64 `kstat:"scatter_sg_table_retry"`
+}
+
+// PoolIO contains I/O statistics of the pool.
+// Statistics of TRIM are reported by OpenZFS 2.1 and newer, statistics of queues by older modules only.
+// Counters of reads and writes are reported by OpenZFS 2.3 and newer and by modules older than 2.1.
+type PoolIO struct {
+	ReadBytes    uint64 `kstat:"nread"`
+	WrittenBytes uint64 `kstat:"nwritten"`
+	Reads        uint64 `kstat:"reads"`
+	Writes       uint64 `kstat:"writes"`
+
+	TrimExtentsWritten     uint64 `kstat:"trim_extents_written"`
+	TrimBytesWritten       uint64 `kstat:"trim_bytes_written"`
+	TrimExtentsSkipped     uint64 `kstat:"trim_extents_skipped"`
+	TrimBytesSkipped       uint64 `kstat:"trim_bytes_skipped"`
+	TrimExtentsFailed      uint64 `kstat:"trim_extents_failed"`
+	TrimBytesFailed        uint64 `kstat:"trim_bytes_failed"`
+	AutotrimExtentsWritten uint64 `kstat:"autotrim_extents_written"`
+	AutotrimBytesWritten   uint64 `kstat:"autotrim_bytes_written"`
+	AutotrimExtentsSkipped uint64 `kstat:"autotrim_extents_skipped"`
+	AutotrimBytesSkipped   uint64 `kstat:"autotrim_bytes_skipped"`
+	AutotrimExtentsFailed  uint64 `kstat:"autotrim_extents_failed"`
+	AutotrimBytesFailed    uint64 `kstat:"autotrim_bytes_failed"`
+
+	// times are reported in nanoseconds
+	WaitTime       uint64 `kstat:"wtime"`
+	WaitLengthTime uint64 `kstat:"wlentime"`
+	WaitUpdate     uint64 `kstat:"wupdate"`
+	RunTime        uint64 `kstat:"rtime"`
+	RunLengthTime  uint64 `kstat:"rlentime"`
+	RunUpdate      uint64 `kstat:"rupdate"`
+
+	WaitCount uint64 `kstat:"wcnt,gauge"`
+	RunCount  uint64 `kstat:"rcnt,gauge"`
+}
+
+// poolIOStats contains counters of reads and writes exported in iostats by OpenZFS 2.3 and newer
+type poolIOStats struct {
+	ARCReads           uint64 `kstat:"arc_read_count"`
+	ARCReadBytes       uint64 `kstat:"arc_read_bytes"`
+	ARCWrites          uint64 `kstat:"arc_write_count"`
+	ARCWrittenBytes    uint64 `kstat:"arc_write_bytes"`
+	DirectReads        uint64 `kstat:"direct_read_count"`
+	DirectReadBytes    uint64 `kstat:"direct_read_bytes"`
+	DirectWrites       uint64 `kstat:"direct_write_count"`
+	DirectWrittenBytes uint64 `kstat:"direct_write_bytes"`
+}
+
+// TXGState is the state of transaction group
+type TXGState string
+
+// Transaction group states
+const (
+	TXGOpen      TXGState = "O"
+	TXGQuiescing TXGState = "Q"
+	TXGWaiting   TXGState = "W"
+	TXGSyncing   TXGState = "S"
+	TXGCommitted TXGState = "C"
+)
+
+// TXG contains statistics of transaction group
+type TXG struct {
+	TXG uint64
+
+	// Birth is the time since boot transaction group was opened at
+	Birth time.Duration
+
+	State        TXGState
+	Dirty        uint64
+	ReadBytes    uint64
+	WrittenBytes uint64
+	Reads        uint64
+	Writes       uint64
+
+	OpenTime    time.Duration
+	QuiesceTime time.Duration
+	WaitTime    time.Duration
+	SyncTime    time.Duration
+}
+
+type txgStats struct {
+	TXG          uint64 `kstat:"txg"`
+	Birth        uint64 `kstat:"birth"`
+	Dirty        uint64 `kstat:"ndirty"`
+	ReadBytes    uint64 `kstat:"nread"`
+	WrittenBytes uint64 `kstat:"nwritten"`
+	Reads        uint64 `kstat:"reads"`
+	Writes       uint64 `kstat:"writes"`
+	OpenTime     uint64 `kstat:"otime"`
+	QuiesceTime  uint64 `kstat:"qtime"`
+	WaitTime     uint64 `kstat:"wtime"`
+	SyncTime     uint64 `kstat:"stime"`
+}
+
+func ratio(hits, misses uint64) float64 {
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
@@ -0,0 +1,162 @@
+package zfs
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/outofforest/parallel"
+	"github.com/pkg/errors"
+)
+
+// ScrubAction is the action passed to Scrub method
+type ScrubAction int
+
+// Scrub actions
+const (
+	ScrubStart ScrubAction = iota
+	ScrubPause
+	ScrubStop
+)
+
+// TrimAction is the action passed to Trim method
+type TrimAction int
+
+// Trim actions
+const (
+	TrimStart TrimAction = iota
+	TrimCancel
+	TrimSuspend
+)
+
+// WaitActivity is the activity Wait method waits for
+type WaitActivity string
+
+// Wait activities
+const (
+	WaitDiscard    WaitActivity = "discard"
+	WaitFree       WaitActivity = "free"
+	WaitInitialize WaitActivity = "initialize"
+	WaitReplace    WaitActivity = "replace"
+	WaitRemove     WaitActivity = "remove"
+	WaitResilver   WaitActivity = "resilver"
+	WaitScrub      WaitActivity = "scrub"
+	WaitTrim       WaitActivity = "trim"
+)
+
+// TrimOptions stores options passed to Trim method
+type TrimOptions struct {
+	Action TrimAction
+
+	// Secure requests secure trim, it is not supported by all the devices
+	Secure bool
+
+	// Rate limits the trim rate in bytes per second per device, 0 means no limit
+	Rate uint64
+
+	// Devices limits trim to particular devices, all the devices are trimmed if empty
+	Devices []string
+}
+
+// WaitOptions stores options passed to Wait method
+type WaitOptions struct {
+	// Activities are the activities to wait for, all the activities are awaited if empty
+	Activities []WaitActivity
+
+	// Progress is called periodically with the current status of the pool while waiting
+	Progress func(status *PoolStatus)
+
+	// Interval is the interval between progress reports, 10 seconds is used if 0
+	Interval time.Duration
+}
+
+// Scrub starts, pauses or stops scrub of ZPool
+func (p *Pool) Scrub(ctx context.Context, action ScrubAction) error {
+	args := []string{"scrub"}
+	switch action {
+	case ScrubStart:
+	case ScrubPause:
+		args = append(args, "-p")
+	case ScrubStop:
+		args = append(args, "-s")
+	default:
+		return errors.Errorf("unknown scrub action %d", action)
+	}
+	_, err := zpool(ctx, append(args, p.Name)...)
+	return err
+}
+
+// Trim starts, cancels or suspends trim of ZPool devices
+func (p *Pool) Trim(ctx context.Context, options TrimOptions) error {
+	args := []string{"trim"}
+	switch options.Action {
+	case TrimStart:
+		if options.Secure {
+			args = append(args, "-d")
+		}
+		if options.Rate > 0 {
+			args = append(args, "-r", strconv.FormatUint(options.Rate, 10))
+		}
+	case TrimCancel:
+		args = append(args, "-c")
+	case TrimSuspend:
+		args = append(args, "-s")
+	default:
+		return errors.Errorf("unknown trim action %d", options.Action)
+	}
+	args = append(append(args, p.Name), options.Devices...)
+	_, err := zpool(ctx, args...)
+	return err
+}
+
+// Wait blocks until the activities running in ZPool are finished or context is canceled
+func (p *Pool) Wait(ctx context.Context, options WaitOptions) error {
+	args := []string{"wait"}
+	if len(options.Activities) > 0 {
+		activities := make([]string, 0, len(options.Activities))
+		for _, a := range options.Activities {
+			activities = append(activities, string(a))
+		}
+		args = append(args, "-t", strings.Join(activities, ","))
+	}
+	args = append(args, p.Name)
+
+	if options.Progress == nil {
+		_, err := zpool(ctx, args...)
+		return err
+	}
+
+	interval := options.Interval
+	if interval == 0 {
+		interval = 10 * time.Second
+	}
+	return parallel.Run(ctx, func(ctx context.Context, spawn parallel.SpawnFn) error {
+		spawn("wait", parallel.Exit, func(ctx context.Context) error {
+			_, err := zpool(ctx, args...)
+			return err
+		})
+		spawn("progress", parallel.Continue, func(ctx context.Context) error {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return errors.WithStack(ctx.Err())
+				case <-ticker.C:
+				}
+
+				status, err := p.Status(ctx)
+				if err != nil {
+					if ctx.Err() != nil {
+						return errors.WithStack(ctx.Err())
+					}
+					return err
+				}
+				options.Progress(status)
+			}
+		})
+		return nil
+	})
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ContentType is the content type of Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// MetricType is the type of metric
+type MetricType string
+
+// Metric types
+const (
+	Gauge   MetricType = "gauge"
+	Counter MetricType = "counter"
+)
+
+// Label is the label of sample
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is the single value of metric
+type Sample struct {
+	Labels []Label
+	Value  float64
+}
+
+// Metric is the family of samples sharing name, help and type
+type Metric struct {
+	Name    string
+	Help    string
+	Type    MetricType
+	Samples []Sample
+}
+
+// Write writes metrics to w in Prometheus text exposition format
+func Write(w io.Writer, metrics []Metric) error {
+	buf := bufio.NewWriter(w)
+	for _, m := range metrics {
+		if len(m.Samples) == 0 {
+			continue
+		}
+		buf.WriteString("# HELP " + m.Name + " " + escapeHelp(m.Help) + "\n")
+		buf.WriteString("# TYPE " + m.Name + " " + string(m.Type) + "\n")
+		for _, s := range m.Samples {
+			buf.WriteString(m.Name)
+			if len(s.Labels) > 0 {
+				buf.WriteString("{")
+				for i, l := range s.Labels {
+					if i > 0 {
+						buf.WriteString(",")
+					}
+					buf.WriteString(l.Name + `="` + escapeLabel(l.Value) + `"`)
+				}
+				buf.WriteString("}")
+			}
+			buf.WriteString(" " + formatValue(s.Value) + "\n")
+		}
+	}
+	return errors.WithStack(buf.Flush())
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelReplacer.Replace(s)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,286 @@
+// Package metrics exports statistics of ZFS pools and datasets in Prometheus format.
+package metrics
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/outofforest/go-zfs/v3"
+	"github.com/outofforest/go-zfs/v3/kstat"
+	"github.com/pkg/errors"
+)
+
+var (
+	poolHealthStates = []string{"ONLINE", "DEGRADED", "FAULTED", "OFFLINE", "UNAVAIL", "REMOVED", "SUSPENDED"}
+	scrubStates      = []string{"none", zfs.ScanScanning, zfs.ScanFinished, zfs.ScanCanceled, zfs.ScanPaused}
+)
+
+// Config is the configuration of collector
+type Config struct {
+	// Include are the patterns of datasets to report, all the datasets are reported if empty.
+	// Patterns use the syntax of path.Match.
+	Include []string
+
+	// Exclude are the patterns of datasets not to report, they take precedence over Include
+	Exclude []string
+
+	// KStatRoot is the directory ARC statistics are read from, kstat.DefaultRoot is used if empty
+	KStatRoot string
+}
+
+// Collector collects metrics of ZFS
+type Collector struct {
+	config Config
+	kstat  *kstat.Reader
+}
+
+type collector struct {
+	Name    string
+	Collect func(ctx context.Context) ([]Metric, error)
+}
+
+// New returns new collector
+func New(config Config) (*Collector, error) {
+	for _, pattern := range append(append([]string{}, config.Include...), config.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid dataset pattern %q", pattern)
+		}
+	}
+	return &Collector{
+		config: config,
+		kstat:  kstat.NewReader(config.KStatRoot),
+	}, nil
+}
+
+// ServeHTTP serves metrics in Prometheus text exposition format.
+// Failures of collectors are reported by zfs_scrape_error metric.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	metrics, _ := c.Collect(r.Context())
+	w.Header().Set("Content-Type", ContentType)
+	_ = Write(w, metrics)
+}
+
+// Collect collects metrics. Metrics are returned even if some collectors fail,
+// each failure is reported by zfs_scrape_error metric and the first one is returned as an error.
+func (c *Collector) Collect(ctx context.Context) ([]Metric, error) {
+	return collect(ctx, []collector{
+		{Name: "pools", Collect: c.collectPools},
+		{Name: "datasets", Collect: c.collectDatasets},
+		{Name: "arc", Collect: func(ctx context.Context) ([]Metric, error) {
+			return c.collectARC()
+		}},
+	})
+}
+
+func collect(ctx context.Context, collectors []collector) ([]Metric, error) {
+	var (
+		metrics     []Metric
+		scrapeError = Metric{Name: "zfs_scrape_error", Help: "Whether collector failed during the scrape", Type: Gauge}
+		firstErr    error
+	)
+	for _, collector := range collectors {
+		collected, err := collector.Collect(ctx)
+		if err != nil && firstErr == nil {
+			firstErr = errors.Wrapf(err, "collector %q failed", collector.Name)
+		}
+		metrics = append(metrics, collected...)
+		scrapeError.Samples = append(scrapeError.Samples, Sample{
+			Labels: []Label{{Name: "collector", Value: collector.Name}},
+			Value:  boolValue(err != nil),
+		})
+	}
+	return append(metrics, scrapeError), firstErr
+}
+
+func (c *Collector) collectPools(ctx context.Context) ([]Metric, error) {
+	var (
+		health        = Metric{Name: "zfs_pool_health", Help: "Health of the pool", Type: Gauge}
+		size          = Metric{Name: "zfs_pool_size_bytes", Help: "Size of the pool", Type: Gauge}
+		allocated     = Metric{Name: "zfs_pool_allocated_bytes", Help: "Space allocated in the pool", Type: Gauge}
+		free          = Metric{Name: "zfs_pool_free_bytes", Help: "Free space in the pool", Type: Gauge}
+		capacity      = Metric{Name: "zfs_pool_capacity_ratio", Help: "Ratio of allocated space to the size of the pool", Type: Gauge}
+		fragmentation = Metric{Name: "zfs_pool_fragmentation_ratio", Help: "Fragmentation of free space in the pool", Type: Gauge}
+		scrubState    = Metric{Name: "zfs_pool_scrub_state", Help: "State of the last scrub", Type: Gauge}
+		scrubProgress = Metric{Name: "zfs_pool_scrub_progress_ratio", Help: "Progress of the last scrub", Type: Gauge}
+		scrubErrors   = Metric{Name: "zfs_pool_scrub_errors", Help: "Errors found by the last scrub", Type: Gauge}
+		scrubEnd      = Metric{Name: "zfs_pool_scrub_end_time_seconds", Help: "Time the last scrub finished at", Type: Gauge}
+	)
+
+	pools, err := zfs.Pools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// failure of one pool doesn't prevent other ones from being reported
+	var firstErr error
+	for _, pool := range pools {
+		labels := []Label{{Name: "pool", Value: pool.Name}}
+
+		props, err := pool.Properties(ctx)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for _, state := range poolHealthStates {
+			health.Samples = append(health.Samples, Sample{
+				Labels: append(labels, Label{Name: "state", Value: state}),
+				Value:  boolValue(props.Health == state),
+			})
+		}
+		size.Samples = append(size.Samples, Sample{Labels: labels, Value: float64(props.Size)})
+		allocated.Samples = append(allocated.Samples, Sample{Labels: labels, Value: float64(props.Allocated)})
+		free.Samples = append(free.Samples, Sample{Labels: labels, Value: float64(props.Free)})
+		capacity.Samples = append(capacity.Samples, Sample{Labels: labels, Value: float64(props.Capacity) / 100})
+		fragmentation.Samples = append(fragmentation.Samples,
+			Sample{Labels: labels, Value: float64(props.Fragmentation) / 100})
+
+		status, err := pool.Status(ctx)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		state := "none"
+		if status.Scan != nil && status.Scan.Function == zfs.ScanScrub {
+			state = status.Scan.State
+			scrubProgress.Samples = append(scrubProgress.Samples,
+				Sample{Labels: labels, Value: status.Scan.PercentDone / 100})
+			scrubErrors.Samples = append(scrubErrors.Samples, Sample{Labels: labels, Value: float64(status.Scan.Errors)})
+			if !status.Scan.EndTime.IsZero() {
+				scrubEnd.Samples = append(scrubEnd.Samples,
+					Sample{Labels: labels, Value: float64(status.Scan.EndTime.Unix())})
+			}
+		}
+		for _, s := range scrubStates {
+			scrubState.Samples = append(scrubState.Samples, Sample{
+				Labels: append(labels, Label{Name: "state", Value: s}),
+				Value:  boolValue(state == s),
+			})
+		}
+	}
+	return []Metric{health, size, allocated, free, capacity, fragmentation, scrubState, scrubProgress, scrubErrors,
+		scrubEnd}, firstErr
+}
+
+func (c *Collector) collectDatasets(ctx context.Context) ([]Metric, error) {
+	var (
+		used        = Metric{Name: "zfs_dataset_used_bytes", Help: "Space used by the dataset and its descendants", Type: Gauge}
+		available   = Metric{Name: "zfs_dataset_available_bytes", Help: "Space available to the dataset", Type: Gauge}
+		referenced  = Metric{Name: "zfs_dataset_referenced_bytes", Help: "Space referenced by the dataset", Type: Gauge}
+		written     = Metric{Name: "zfs_dataset_written_bytes", Help: "Space written since the previous snapshot", Type: Gauge}
+		snapshots   = Metric{Name: "zfs_dataset_snapshots", Help: "Number of snapshots of the dataset", Type: Gauge}
+		oldestAge   = Metric{Name: "zfs_dataset_oldest_snapshot_age_seconds", Help: "Age of the oldest snapshot", Type: Gauge}
+		newestAge   = Metric{Name: "zfs_dataset_newest_snapshot_age_seconds", Help: "Age of the newest snapshot", Type: Gauge}
+		datasetTime = map[string][]time.Time{}
+	)
+
+	filesystems, err := zfs.Filesystems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	volumes, err := zfs.Volumes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]zfs.Info, 0, len(filesystems)+len(volumes))
+	for _, filesystem := range filesystems {
+		infos = append(infos, filesystem.Info)
+	}
+	for _, volume := range volumes {
+		infos = append(infos, volume.Info)
+	}
+
+	snaps, err := zfs.Snapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range snaps {
+		dataset, _, _ := strings.Cut(s.Info.Name, "@")
+		datasetTime[dataset] = append(datasetTime[dataset], s.Info.Creation)
+	}
+
+	now := time.Now()
+	for _, info := range infos {
+		if !c.included(info.Name) {
+			continue
+		}
+		labels := []Label{{Name: "dataset", Value: info.Name}}
+		used.Samples = append(used.Samples, Sample{Labels: labels, Value: float64(info.Used)})
+		available.Samples = append(available.Samples, Sample{Labels: labels, Value: float64(info.Avail)})
+		referenced.Samples = append(referenced.Samples, Sample{Labels: labels, Value: float64(info.Referenced)})
+		written.Samples = append(written.Samples, Sample{Labels: labels, Value: float64(info.Written)})
+
+		times := datasetTime[info.Name]
+		snapshots.Samples = append(snapshots.Samples, Sample{Labels: labels, Value: float64(len(times))})
+		if len(times) == 0 {
+			continue
+		}
+		sort.Slice(times, func(i, j int) bool {
+			return times[i].Before(times[j])
+		})
+		oldestAge.Samples = append(oldestAge.Samples, Sample{Labels: labels, Value: now.Sub(times[0]).Seconds()})
+		newestAge.Samples = append(newestAge.Samples,
+			Sample{Labels: labels, Value: now.Sub(times[len(times)-1]).Seconds()})
+	}
+	return []Metric{used, available, referenced, written, snapshots, oldestAge, newestAge}, nil
+}
+
+func (c *Collector) collectARC() ([]Metric, error) {
+	arc, err := c.kstat.ARCStats()
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			// ZFS module is not loaded
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return []Metric{
+		{Name: "zfs_arc_hits_total", Help: "Number of ARC hits", Type: Counter, Samples: value(arc.Hits)},
+		{Name: "zfs_arc_misses_total", Help: "Number of ARC misses", Type: Counter, Samples: value(arc.Misses)},
+		{Name: "zfs_arc_size_bytes", Help: "Current size of ARC", Type: Gauge, Samples: value(arc.Size)},
+		{Name: "zfs_arc_target_size_bytes", Help: "Target size of ARC", Type: Gauge, Samples: value(arc.Target)},
+		{Name: "zfs_arc_target_min_bytes", Help: "Minimum target size of ARC", Type: Gauge, Samples: value(arc.TargetMin)},
+		{Name: "zfs_arc_target_max_bytes", Help: "Maximum target size of ARC", Type: Gauge, Samples: value(arc.TargetMax)},
+		{Name: "zfs_arc_l2_hits_total", Help: "Number of L2ARC hits", Type: Counter, Samples: value(arc.L2Hits)},
+		{Name: "zfs_arc_l2_misses_total", Help: "Number of L2ARC misses", Type: Counter, Samples: value(arc.L2Misses)},
+		{Name: "zfs_arc_l2_size_bytes", Help: "Current size of L2ARC", Type: Gauge, Samples: value(arc.L2Size)},
+	}, nil
+}
+
+// included reports if dataset matches include patterns and doesn't match exclude ones
+func (c *Collector) included(dataset string) bool {
+	for _, pattern := range c.config.Exclude {
+		if matched, _ := path.Match(pattern, dataset); matched {
+			return false
+		}
+	}
+	if len(c.config.Include) == 0 {
+		return true
+	}
+	for _, pattern := range c.config.Include {
+		if matched, _ := path.Match(pattern, dataset); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func value(v uint64) []Sample {
+	return []Sample{{Value: float64(v)}}
+}
+
+func boolValue(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, Write(buf, []Metric{
+		{
+			Name: "zfs_pool_health",
+			Help: "Health of the pool",
+			Type: Gauge,
+			Samples: []Sample{
+				{Labels: []Label{{Name: "pool", Value: "tank"}, {Name: "state", Value: "ONLINE"}}, Value: 1},
+				{Labels: []Label{{Name: "pool", Value: `ta"nk\`}, {Name: "state", Value: "ONLINE"}}, Value: 0},
+			},
+		},
+		{Name: "zfs_empty", Help: "Not written", Type: Gauge},
+		{Name: "zfs_arc_hits_total", Help: "Number of\nARC hits", Type: Counter, Samples: value(1234567890123)},
+	}))
+	assert.Equal(t, `# HELP zfs_pool_health Health of the pool
+# TYPE zfs_pool_health gauge
+zfs_pool_health{pool="tank",state="ONLINE"} 1
+zfs_pool_health{pool="ta\"nk\\",state="ONLINE"} 0
+# HELP zfs_arc_hits_total Number of\nARC hits
+# TYPE zfs_arc_hits_total counter
+zfs_arc_hits_total 1.234567890123e+12
+`, buf.String())
+}
+
+func TestIncluded(t *testing.T) {
+	_, err := New(Config{Include: []string{"["}})
+	require.Error(t, err)
+
+	c, err := New(Config{})
+	require.NoError(t, err)
+	assert.True(t, c.included("tank/fs"))
+
+	c, err = New(Config{Include: []string{"tank", "tank/*"}, Exclude: []string{"tank/tmp"}})
+	require.NoError(t, err)
+	assert.True(t, c.included("tank"))
+	assert.True(t, c.included("tank/fs"))
+	assert.False(t, c.included("tank/tmp"))
+	assert.False(t, c.included("tank/fs/child"))
+	assert.False(t, c.included("other"))
+}
+
+func TestCollectARC(t *testing.T) {
+	root := t.TempDir()
+	c, err := New(Config{KStatRoot: root})
+	require.NoError(t, err)
+
+	metrics, err := c.collectARC()
+	require.NoError(t, err)
+	assert.Empty(t, metrics)
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "arcstats"), []byte(`13 1 0x01 147 39984 3989349925 1064493402879766
+name                            type data
+hits                            4    900
+misses                          4    100
+size                            4    4096
+`), 0o600))
+	metrics, err = c.collectARC()
+	require.NoError(t, err)
+	values := map[string]float64{}
+	for _, m := range metrics {
+		require.Len(t, m.Samples, 1)
+		values[m.Name] = m.Samples[0].Value
+	}
+	assert.Equal(t, 900.0, values["zfs_arc_hits_total"])
+	assert.Equal(t, 100.0, values["zfs_arc_misses_total"])
+	assert.Equal(t, 4096.0, values["zfs_arc_size_bytes"])
+}
+
+func TestCollectScrapeErrors(t *testing.T) {
+	metrics, err := collect(context.Background(), []collector{
+		{Name: "ok", Collect: func(ctx context.Context) ([]Metric, error) {
+			return []Metric{{Name: "zfs_ok", Type: Gauge, Samples: value(1)}}, nil
+		}},
+		{Name: "partial", Collect: func(ctx context.Context) ([]Metric, error) {
+			return []Metric{{Name: "zfs_partial", Type: Gauge, Samples: value(2)}}, errors.New("pool failed")
+		}},
+		{Name: "failed", Collect: func(ctx context.Context) ([]Metric, error) {
+			return nil, errors.New("listing failed")
+		}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "pool failed")
+
+	require.Len(t, metrics, 3)
+	assert.Equal(t, "zfs_ok", metrics[0].Name)
+	assert.Equal(t, "zfs_partial", metrics[1].Name)
+	assert.Equal(t, Metric{
+		Name: "zfs_scrape_error",
+		Help: "Whether collector failed during the scrape",
+		Type: Gauge,
+		Samples: []Sample{
+			{Labels: []Label{{Name: "collector", Value: "ok"}}, Value: 0},
+			{Labels: []Label{{Name: "collector", Value: "partial"}}, Value: 1},
+			{Labels: []Label{{Name: "collector", Value: "failed"}}, Value: 1},
+		},
+	}, metrics[2])
+}
@@ -0,0 +1,258 @@
+// Package params reads and writes parameters of ZFS kernel module.
+package params
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultRoot is the directory where kernel exports parameters of ZFS module
+	DefaultRoot = "/sys/module/zfs/parameters"
+
+	// DefaultConfigFile is the modprobe configuration file parameters are persisted to
+	DefaultConfigFile = "/etc/modprobe.d/zfs.conf"
+
+	module = "zfs"
+)
+
+var nameRegExp = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// Param is the parameter of ZFS module
+type Param struct {
+	Name  string
+	Value string
+}
+
+// Params reads and writes parameters of ZFS module
+type Params struct {
+	root string
+}
+
+// New returns parameters stored in root directory, DefaultRoot is used if root is empty
+func New(root string) *Params {
+	if root == "" {
+		root = DefaultRoot
+	}
+	return &Params{root: root}
+}
+
+// List returns all the parameters sorted by name
+func (p *Params) List() ([]Param, error) {
+	entries, err := os.ReadDir(p.root)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	params := make([]Param, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		value, err := p.Get(e.Name())
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, Param{Name: e.Name(), Value: value})
+	}
+	return params, nil
+}
+
+// Get returns the value of parameter
+func (p *Params) Get(name string) (string, error) {
+	path, err := p.path(name)
+	if err != nil {
+		return "", err
+	}
+	value, err := os.ReadFile(path)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return strings.TrimSpace(string(value)), nil
+}
+
+// GetUint returns the value of unsigned integer parameter
+func (p *Params) GetUint(name string) (uint64, error) {
+	value, err := p.Get(name)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseUint(value, 10, 64)
+	return v, errors.Wrapf(err, "parameter %q is not an unsigned integer", name)
+}
+
+// GetInt returns the value of integer parameter
+func (p *Params) GetInt(name string) (int64, error) {
+	value, err := p.Get(name)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseInt(value, 10, 64)
+	return v, errors.Wrapf(err, "parameter %q is not an integer", name)
+}
+
+// GetBool returns the value of boolean parameter
+func (p *Params) GetBool(name string) (bool, error) {
+	value, err := p.Get(name)
+	if err != nil {
+		return false, err
+	}
+	switch value {
+	case "1", "Y":
+		return true, nil
+	case "0", "N":
+		return false, nil
+	default:
+		return false, errors.Errorf("parameter %q is not a boolean", name)
+	}
+}
+
+// Set sets the value of parameter. Parameter must exist and if its current value is numeric,
+// the new one must be numeric too.
+func (p *Params) Set(name, value string) error {
+	current, err := p.Get(name)
+	if err != nil {
+		return err
+	}
+	if err := validate(name, current, value); err != nil {
+		return err
+	}
+	path, err := p.path(name)
+	if err != nil {
+		return err
+	}
+	return errors.WithStack(os.WriteFile(path, []byte(value), 0o644))
+}
+
+// SetUint sets the value of unsigned integer parameter
+func (p *Params) SetUint(name string, value uint64) error {
+	return p.Set(name, strconv.FormatUint(value, 10))
+}
+
+// SetInt sets the value of integer parameter
+func (p *Params) SetInt(name string, value int64) error {
+	return p.Set(name, strconv.FormatInt(value, 10))
+}
+
+// SetBool sets the value of boolean parameter
+func (p *Params) SetBool(name string, value bool) error {
+	if value {
+		return p.Set(name, "1")
+	}
+	return p.Set(name, "0")
+}
+
+func (p *Params) path(name string) (string, error) {
+	if !nameRegExp.MatchString(name) {
+		return "", errors.Errorf("invalid parameter name %q", name)
+	}
+	return filepath.Join(p.root, name), nil
+}
+
+func validate(name, current, value string) error {
+	if value == "" || strings.ContainsAny(value, " \t\r\n") {
+		return errors.Errorf("invalid value %q of parameter %q", value, name)
+	}
+	if _, err := strconv.ParseInt(current, 10, 64); err != nil {
+		if _, err := strconv.ParseUint(current, 10, 64); err != nil {
+			return nil
+		}
+	}
+	if _, err := strconv.ParseInt(value, 0, 64); err != nil {
+		if _, err := strconv.ParseUint(value, 0, 64); err != nil {
+			return errors.Errorf("parameter %q requires numeric value, got %q", name, value)
+		}
+	}
+	return nil
+}
+
+// Persisted returns parameters of ZFS module stored in modprobe configuration file
+func Persisted(file string) (map[string]string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	params := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		for _, param := range moduleOptions(scanner.Text()) {
+			name, value, _ := strings.Cut(param, "=")
+			params[name] = value
+		}
+	}
+	return params, errors.WithStack(scanner.Err())
+}
+
+// Persist stores parameters in modprobe configuration file so they are applied when module is loaded.
+// Parameters already stored in the file are preserved unless overwritten, other content is kept intact.
+func Persist(file string, params map[string]string) error {
+	for name, value := range params {
+		if !nameRegExp.MatchString(name) {
+			return errors.Errorf("invalid parameter name %q", name)
+		}
+		if value == "" || strings.ContainsAny(value, " \t\r\n") {
+			return errors.Errorf("invalid value %q of parameter %q", value, name)
+		}
+	}
+
+	persisted, err := Persisted(file)
+	if err != nil {
+		return err
+	}
+	for name, value := range params {
+		persisted[name] = value
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+
+	out := &bytes.Buffer{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if len(moduleOptions(scanner.Text())) > 0 {
+			continue
+		}
+		out.WriteString(scanner.Text())
+		out.WriteString("\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	names := make([]string, 0, len(persisted))
+	for name := range persisted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		out.WriteString("options " + module + " " + name + "=" + persisted[name] + "\n")
+	}
+
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, out.Bytes(), 0o644); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(tmp, file))
+}
+
+// moduleOptions returns name=value pairs from the "options zfs" line of modprobe configuration
+func moduleOptions(line string) []string {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[0] != "options" || fields[1] != module {
+		return nil
+	}
+	return fields[2:]
+}
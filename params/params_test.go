@@ -0,0 +1,88 @@
+package params
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fakeSysfs(t *testing.T) string {
+	root := t.TempDir()
+	for name, value := range map[string]string{
+		"zfs_arc_max":          "0\n",
+		"zfs_txg_timeout":      "5\n",
+		"zfs_prefetch_disable": "0\n",
+		"zfs_vdev_scheduler":   "noop\n",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(value), 0o600))
+	}
+	return root
+}
+
+func TestParams(t *testing.T) {
+	p := New(fakeSysfs(t))
+
+	params, err := p.List()
+	require.NoError(t, err)
+	assert.Equal(t, []Param{
+		{Name: "zfs_arc_max", Value: "0"},
+		{Name: "zfs_prefetch_disable", Value: "0"},
+		{Name: "zfs_txg_timeout", Value: "5"},
+		{Name: "zfs_vdev_scheduler", Value: "noop"},
+	}, params)
+
+	require.NoError(t, p.SetUint("zfs_arc_max", 4*1024*1024*1024))
+	arcMax, err := p.GetUint("zfs_arc_max")
+	require.NoError(t, err)
+	assert.EqualValues(t, 4*1024*1024*1024, arcMax)
+
+	require.NoError(t, p.SetBool("zfs_prefetch_disable", true))
+	disabled, err := p.GetBool("zfs_prefetch_disable")
+	require.NoError(t, err)
+	assert.True(t, disabled)
+
+	require.NoError(t, p.SetInt("zfs_txg_timeout", 10))
+	timeout, err := p.GetInt("zfs_txg_timeout")
+	require.NoError(t, err)
+	assert.EqualValues(t, 10, timeout)
+
+	require.NoError(t, p.Set("zfs_vdev_scheduler", "none"))
+	_, err = p.GetUint("zfs_vdev_scheduler")
+	require.Error(t, err)
+
+	require.Error(t, p.Set("zfs_arc_max", "lots"))
+	require.Error(t, p.Set("zfs_arc_max", "1 2"))
+	require.Error(t, p.Set("zfs_missing", "1"))
+	require.Error(t, p.Set("../zfs_arc_max", "1"))
+}
+
+func TestPersist(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "zfs.conf")
+
+	params, err := Persisted(file)
+	require.NoError(t, err)
+	assert.Empty(t, params)
+
+	require.NoError(t, os.WriteFile(file, []byte("# tuning\noptions spl spl_hostid=1\noptions zfs zfs_arc_max=1024 zfs_txg_timeout=5\n"), 0o600))
+	require.NoError(t, Persist(file, map[string]string{"zfs_arc_max": "2048", "zfs_arc_min": "512"}))
+
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "# tuning\noptions spl spl_hostid=1\n"+
+		"options zfs zfs_arc_max=2048\n"+
+		"options zfs zfs_arc_min=512\n"+
+		"options zfs zfs_txg_timeout=5\n", string(content))
+
+	params, err = Persisted(file)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"zfs_arc_max":     "2048",
+		"zfs_arc_min":     "512",
+		"zfs_txg_timeout": "5",
+	}, params)
+
+	require.Error(t, Persist(file, map[string]string{"zfs_arc_max": "1\noptions zfs x=1"}))
+}
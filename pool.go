@@ -1,6 +1,12 @@
 package zfs
 
-import "context"
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
 
 // Pools returns list of imported ZPools
 func Pools(ctx context.Context) ([]*Pool, error) {
@@ -26,23 +32,196 @@ func GetPool(ctx context.Context, name string) (*Pool, error) {
 	return &Pool{Name: name}, nil
 }
 
-// ImportPool imports ZPool
-func ImportPool(ctx context.Context, name string) (*Pool, error) {
-	_, err := zpool(ctx, "import", name)
+// CreatePoolOptions stores options passed to CreatePool function
+type CreatePoolOptions struct {
+	// Properties are the properties of the pool
+	Properties map[string]string
+
+	// FilesystemProperties are the properties of the root dataset of the pool
+	FilesystemProperties map[string]string
+
+	// Mountpoint is the mountpoint of the root dataset, default is /<pool>
+	Mountpoint string
+
+	// Altroot is the alternate root directory of the pool
+	Altroot string
+
+	// Force allows using devices which seem to be in use or have mismatching replication levels
+	Force bool
+}
+
+// CreatePool creates ZPool
+func CreatePool(ctx context.Context, name string, layout PoolLayout, options CreatePoolOptions) (*Pool, error) {
+	vdevs, err := layout.args()
 	if err != nil {
 		return nil, err
 	}
 
+	args := []string{"create"}
+	if options.Force {
+		args = append(args, "-f")
+	}
+	if options.Mountpoint != "" {
+		args = append(args, "-m", options.Mountpoint)
+	}
+	if options.Altroot != "" {
+		args = append(args, "-R", options.Altroot)
+	}
+	for k, v := range options.Properties {
+		args = append(args, "-o", k+"="+v)
+	}
+	for k, v := range options.FilesystemProperties {
+		args = append(args, "-O", k+"="+v)
+	}
+	args = append(append(args, name), vdevs...)
+	if _, err := zpool(ctx, args...); err != nil {
+		return nil, err
+	}
+
 	return &Pool{Name: name}, nil
 }
 
+// ImportOptions stores options passed to ImportPoolWithOptions function
+type ImportOptions struct {
+	// SearchDirs are the directories searched for devices, by default devices in /dev are used
+	SearchDirs []string
+
+	// Altroot is the alternate root directory of the pool
+	Altroot string
+
+	// ReadOnly imports pool in read-only mode
+	ReadOnly bool
+
+	// NoMount prevents filesystems from being mounted
+	NoMount bool
+
+	// NewName is the name pool is imported under, original name is used if empty
+	NewName string
+
+	// Force imports pool even if it seems to be in use by another system
+	Force bool
+
+	// Rewind discards the last few transactions if pool can't be imported otherwise
+	Rewind bool
+
+	// MissingLog allows importing pool with missing log device
+	MissingLog bool
+
+	// RewindToCheckpoint rewinds pool to its checkpoint, all the changes made after the checkpoint are lost
+	RewindToCheckpoint bool
+
+	// Properties are the properties set on imported pool
+	Properties map[string]string
+}
+
+// ImportablePools returns pools available for import found in the search directories,
+// devices in /dev are searched if no directory is provided
+func ImportablePools(ctx context.Context, searchDirs []string) ([]*PoolStatus, error) {
+	args := []string{"import"}
+	for _, dir := range searchDirs {
+		args = append(args, "-d", dir)
+	}
+	out, err := zpoolOutput(ctx, args...)
+	if err != nil {
+		var cmdErr *cmdError
+		if errors.As(err, &cmdErr) && strings.Contains(cmdErr.Stderr, "no pools available to import") {
+			return []*PoolStatus{}, nil
+		}
+		return nil, err
+	}
+	return parseStatusText(out)
+}
+
+// ImportPool imports ZPool
+func ImportPool(ctx context.Context, name string) (*Pool, error) {
+	return ImportPoolWithOptions(ctx, name, ImportOptions{})
+}
+
+// ImportPoolWithOptions imports ZPool using options, pool may be identified by its name or GUID
+func ImportPoolWithOptions(ctx context.Context, pool string, options ImportOptions) (*Pool, error) {
+	args := []string{"import"}
+	for _, dir := range options.SearchDirs {
+		args = append(args, "-d", dir)
+	}
+	if options.Altroot != "" {
+		args = append(args, "-R", options.Altroot)
+	}
+	if options.ReadOnly {
+		args = append(args, "-o", "readonly=on")
+	}
+	for k, v := range options.Properties {
+		args = append(args, "-o", k+"="+v)
+	}
+	if options.NoMount {
+		args = append(args, "-N")
+	}
+	if options.Force {
+		args = append(args, "-f")
+	}
+	if options.Rewind {
+		args = append(args, "-F")
+	}
+	if options.MissingLog {
+		args = append(args, "-m")
+	}
+	if options.RewindToCheckpoint {
+		args = append(args, "--rewind-to-checkpoint")
+	}
+	args = append(args, pool)
+	if options.NewName != "" {
+		args = append(args, options.NewName)
+	}
+
+	if _, err := zpool(ctx, args...); err != nil {
+		return nil, err
+	}
+
+	if options.NewName != "" {
+		return &Pool{Name: options.NewName}, nil
+	}
+	if _, err := strconv.ParseUint(pool, 10, 64); err == nil {
+		// Pool was imported by GUID so its name has to be found
+		out, err := zpool(ctx, "list", "-H", "-o", "name,guid")
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range out {
+			if line[1] == pool {
+				return &Pool{Name: line[0]}, nil
+			}
+		}
+	}
+	return &Pool{Name: pool}, nil
+}
+
 // Pool represents ZPool
 type Pool struct {
 	Name string
 }
 
+// Destroy destroys ZPool
+func (p *Pool) Destroy(ctx context.Context) error {
+	_, err := zpool(ctx, "destroy", p.Name)
+	return err
+}
+
+// ExportOptions stores options passed to ExportWithOptions method
+type ExportOptions struct {
+	// Force unmounts filesystems even if they are in use
+	Force bool
+}
+
 // Export exports ZPool
 func (p *Pool) Export(ctx context.Context) error {
-	_, err := zpool(ctx, "export", p.Name)
+	return p.ExportWithOptions(ctx, ExportOptions{})
+}
+
+// ExportWithOptions exports ZPool using options
+func (p *Pool) ExportWithOptions(ctx context.Context, options ExportOptions) error {
+	args := []string{"export"}
+	if options.Force {
+		args = append(args, "-f")
+	}
+	_, err := zpool(ctx, append(args, p.Name)...)
 	return err
 }
@@ -0,0 +1,144 @@
+package zfs
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const featurePrefix = "feature@"
+
+var poolPropListOptions = strings.Join([]string{"size", "allocated", "free", "fragmentation", "capacity", "health",
+	"dedupratio", "guid", "altroot", "readonly", "autotrim", "autoexpand", "autoreplace", "freeing", "leaked",
+	"expandsize", "ashift", "comment"}, ",")
+
+// PoolProperties contains the most important properties of ZPool
+type PoolProperties struct {
+	Size          uint64
+	Allocated     uint64
+	Free          uint64
+	Fragmentation uint64
+	Capacity      uint64
+	Health        string
+	DedupRatio    float64
+	GUID          uint64
+	Altroot       string
+	ReadOnly      bool
+	Autotrim      bool
+	Autoexpand    bool
+	Autoreplace   bool
+	Freeing       uint64
+	Leaked        uint64
+	ExpandSize    uint64
+	Ashift        uint64
+	Comment       string
+}
+
+// PoolFeature is the feature flag of ZPool
+type PoolFeature struct {
+	Name string
+
+	// State is one of disabled, enabled or active
+	State string
+}
+
+// Properties returns properties of ZPool
+func (p *Pool) Properties(ctx context.Context) (PoolProperties, error) {
+	out, err := zpool(ctx, "get", "-H", "-p", "-o", "property,value", poolPropListOptions, p.Name)
+	if err != nil {
+		return PoolProperties{}, err
+	}
+
+	var props PoolProperties
+	for _, line := range out {
+		if err := props.set(line[0], line[1]); err != nil {
+			return PoolProperties{}, errors.Wrapf(err, "parsing pool property %q failed", line[0])
+		}
+	}
+	return props, nil
+}
+
+func (p *PoolProperties) set(key, value string) error {
+	switch key {
+	case "size":
+		return setUint(&p.Size, value)
+	case "allocated":
+		return setUint(&p.Allocated, value)
+	case "free":
+		return setUint(&p.Free, value)
+	case "fragmentation":
+		return setUint(&p.Fragmentation, strings.TrimSuffix(value, "%"))
+	case "capacity":
+		return setUint(&p.Capacity, strings.TrimSuffix(value, "%"))
+	case "health":
+		setString(&p.Health, value)
+	case "dedupratio":
+		ratio, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		p.DedupRatio = ratio
+	case "guid":
+		return setUint(&p.GUID, value)
+	case "altroot":
+		setString(&p.Altroot, value)
+	case "readonly":
+		p.ReadOnly = value == "on"
+	case "autotrim":
+		p.Autotrim = value == "on"
+	case "autoexpand":
+		p.Autoexpand = value == "on"
+	case "autoreplace":
+		p.Autoreplace = value == "on"
+	case "freeing":
+		return setUint(&p.Freeing, value)
+	case "leaked":
+		return setUint(&p.Leaked, value)
+	case "expandsize":
+		return setUint(&p.ExpandSize, value)
+	case "ashift":
+		return setUint(&p.Ashift, value)
+	case "comment":
+		setString(&p.Comment, value)
+	}
+	return nil
+}
+
+// SetProperty sets ZPool property
+func (p *Pool) SetProperty(ctx context.Context, key, val string) error {
+	_, err := zpool(ctx, "set", key+"="+val, p.Name)
+	return err
+}
+
+// GetProperty returns the current value of ZPool property
+func (p *Pool) GetProperty(ctx context.Context, key string) (string, bool, error) {
+	out, err := zpool(ctx, "get", "-H", "-o", "value,source", key, p.Name)
+	if err != nil {
+		return "", false, err
+	}
+	value := out[0][0]
+	source := out[0][1]
+	if value == "-" && source == "-" {
+		return "", false, nil
+	}
+
+	return value, true, nil
+}
+
+// Features returns feature flags of ZPool
+func (p *Pool) Features(ctx context.Context) ([]PoolFeature, error) {
+	out, err := zpool(ctx, "get", "-H", "-o", "property,value", "all", p.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	features := []PoolFeature{}
+	for _, line := range out {
+		if strings.HasPrefix(line[0], featurePrefix) {
+			features = append(features, PoolFeature{Name: strings.TrimPrefix(line[0], featurePrefix), State: line[1]})
+		}
+	}
+	return features, nil
+}
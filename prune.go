@@ -0,0 +1,67 @@
+package zfs
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// PrunePolicy defines snapshots retained by Prune method
+type PrunePolicy struct {
+	// Prefix limits pruning to snapshots with names starting with it, other snapshots are always kept
+	Prefix string
+
+	// KeepLast is the number of the newest snapshots to keep
+	KeepLast int
+
+	// KeepWithin keeps snapshots created within this duration before now
+	KeepWithin time.Duration
+}
+
+// Prune destroys snapshots of the filesystem not retained by the policy and returns them.
+// If dryRun is set snapshots are returned without being destroyed.
+func (d *Filesystem) Prune(ctx context.Context, policy PrunePolicy, dryRun bool) ([]*Snapshot, error) {
+	if policy.KeepLast <= 0 && policy.KeepWithin <= 0 {
+		return nil, errors.New("prune policy must retain snapshots by count or age")
+	}
+
+	snapshots, err := d.Snapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// zfs lists snapshots in the order of creation (createtxg), creation property has one-second resolution
+	// so it can't be used to order snapshots taken within the same second
+	candidates := make([]*Snapshot, 0, len(snapshots))
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		if strings.HasPrefix(snapshotName(snapshots[i].Info.Name), policy.Prefix) {
+			candidates = append(candidates, snapshots[i])
+		}
+	}
+
+	now := time.Now()
+	pruned := []*Snapshot{}
+	names := []string{}
+	for i, s := range candidates {
+		if i < policy.KeepLast || (policy.KeepWithin > 0 && now.Sub(s.Info.Creation) <= policy.KeepWithin) {
+			continue
+		}
+		pruned = append(pruned, s)
+		names = append(names, snapshotName(s.Info.Name))
+	}
+	if dryRun || len(names) == 0 {
+		return pruned, nil
+	}
+	if _, err := d.DestroySnapshotsByName(ctx, names, DestroyDefault); err != nil {
+		return nil, err
+	}
+	return pruned, nil
+}
+
+// snapshotName returns the part of snapshot name following @
+func snapshotName(name string) string {
+	_, short, _ := strings.Cut(name, "@")
+	return short
+}
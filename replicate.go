@@ -0,0 +1,86 @@
+package zfs
+
+import (
+	"context"
+	"io"
+
+	"github.com/outofforest/parallel"
+	"github.com/pkg/errors"
+)
+
+// ReplicateOptions stores options passed to Replicate function
+type ReplicateOptions struct {
+	Raw        bool
+	Properties bool
+
+	// KeyProvider is used to load the key of the target filesystem if encrypted stream is received
+	KeyProvider KeyProvider
+
+	// Force rolls the target filesystem back to the common snapshot if it has been modified since,
+	// snapshots of target created after the common snapshot are destroyed
+	Force bool
+}
+
+// Replicate sends the newest snapshot of source filesystem to target filesystem and returns the received snapshot.
+// If target exists, incremental stream from the newest snapshot present in both filesystems is sent,
+// including all the intermediate snapshots, otherwise target is created from the full stream of the newest snapshot.
+func Replicate(ctx context.Context, source *Filesystem, target string, options ReplicateOptions) (*Snapshot, error) {
+	snapshots, err := source.Snapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, errors.Errorf("filesystem %q has no snapshots", source.Info.Name)
+	}
+	latest := snapshots[len(snapshots)-1]
+
+	sendOptions := SendOptions{Raw: options.Raw, Properties: options.Properties, Intermediate: true}
+	targetFS, err := GetFilesystem(ctx, target)
+	switch {
+	case err == nil:
+		targetSnapshots, err := targetFS.Snapshots(ctx)
+		if err != nil {
+			return nil, err
+		}
+		existing := map[string]*Snapshot{}
+		for _, s := range targetSnapshots {
+			existing[snapshotName(s.Info.Name)] = s
+		}
+		for i := len(snapshots) - 1; i >= 0; i-- {
+			name := snapshotName(snapshots[i].Info.Name)
+			if _, exists := existing[name]; !exists {
+				continue
+			}
+			if i == len(snapshots)-1 {
+				// target is up to date
+				return existing[name], nil
+			}
+			sendOptions.IncrementFrom = snapshots[i]
+			break
+		}
+		if sendOptions.IncrementFrom == nil {
+			return nil, errors.Errorf("filesystems %q and %q have no common snapshot", source.Info.Name, target)
+		}
+	case !IsNotExist(err):
+		return nil, err
+	}
+
+	var received *Snapshot
+	r, w := io.Pipe()
+	err = parallel.Run(ctx, func(ctx context.Context, spawn parallel.SpawnFn) error {
+		spawn("send", parallel.Continue, func(ctx context.Context) error {
+			return latest.Send(ctx, sendOptions, w)
+		})
+		spawn("receive", parallel.Exit, func(ctx context.Context) error {
+			var err error
+			received, err = ReceiveSnapshotWithOptions(ctx, r, target+"@"+snapshotName(latest.Info.Name),
+				ReceiveOptions{KeyProvider: options.KeyProvider, Force: options.Force})
+			return err
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return received, nil
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"io"
 	"math"
+	"strings"
 )
 
 const datasetSnapshot = "snapshot"
@@ -14,11 +15,29 @@ type CloneOptions struct {
 	Properties map[string]string
 }
 
+// ReceiveOptions stores options passed to ReceiveSnapshotWithOptions function
+type ReceiveOptions struct {
+	// KeyProvider is used to load the key of the received filesystem if encrypted stream is received
+	KeyProvider KeyProvider
+
+	// Force rolls the target filesystem back to its most recent snapshot before receiving incremental stream
+	Force bool
+
+	// NoMount causes the received filesystem not to be mounted
+	NoMount bool
+
+	// ExcludeProperties are the properties not received from the stream, the values are inherited instead
+	ExcludeProperties []string
+}
+
 // SendOptions is the set of options available for Send command
 type SendOptions struct {
 	Raw           bool
 	Properties    bool
 	IncrementFrom *Snapshot
+
+	// Intermediate causes all the snapshots between IncrementFrom and the sent one to be included in the stream
+	Intermediate bool
 }
 
 // Snapshots returns a slice of ZFS snapshots.
@@ -54,10 +73,30 @@ func GetSnapshot(ctx context.Context, name string) (*Snapshot, error) {
 // new snapshot with the specified name, and streams the input data into the
 // newly-created snapshot.
 func ReceiveSnapshot(ctx context.Context, input io.ReadCloser, name string) (*Snapshot, error) {
+	return ReceiveSnapshotWithOptions(ctx, input, name, ReceiveOptions{})
+}
+
+// ReceiveSnapshotWithOptions receives a ZFS stream like ReceiveSnapshot does, using options
+func ReceiveSnapshotWithOptions(ctx context.Context, input io.ReadCloser, name string, options ReceiveOptions) (*Snapshot, error) {
 	defer input.Close()
-	if _, err := zfsStdin(ctx, input, "receive", name); err != nil {
+	args := []string{"receive"}
+	if options.Force {
+		args = append(args, "-F")
+	}
+	if options.NoMount {
+		args = append(args, "-u")
+	}
+	for _, prop := range options.ExcludeProperties {
+		args = append(args, "-x", prop)
+	}
+	if _, err := zfsStdin(ctx, input, append(args, name)...); err != nil {
 		return nil, err
 	}
+	if options.KeyProvider != nil {
+		if err := loadKeyIfNeeded(ctx, strings.SplitN(name, "@", 2)[0], options.KeyProvider); err != nil {
+			return nil, err
+		}
+	}
 	return GetSnapshot(ctx, name)
 }
 
@@ -118,7 +157,11 @@ func (d *Snapshot) Send(ctx context.Context, options SendOptions, output io.Writ
 		args = append(args, "--props")
 	}
 	if options.IncrementFrom != nil {
-		args = append(args, "-i", options.IncrementFrom.Info.Name)
+		flag := "-i"
+		if options.Intermediate {
+			flag = "-I"
+		}
+		args = append(args, flag, options.IncrementFrom.Info.Name)
 	}
 	args = append(args, d.Info.Name)
 	return zfsStdout(ctx, output, args...)
@@ -0,0 +1,580 @@
+package zfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Scan functions
+const (
+	ScanScrub    = "scrub"
+	ScanResilver = "resilver"
+)
+
+// Scan states
+const (
+	ScanScanning = "scanning"
+	ScanFinished = "finished"
+	ScanCanceled = "canceled"
+	ScanPaused   = "paused"
+)
+
+// VdevStatus is the status of virtual device
+type VdevStatus struct {
+	Name           string
+	State          string
+	ReadErrors     uint64
+	WriteErrors    uint64
+	ChecksumErrors uint64
+
+	// Message is the additional information printed next to the device, e.g. (resilvering)
+	Message string
+
+	Children []*VdevStatus
+}
+
+// ScanStatus is the status of scrub or resilver
+type ScanStatus struct {
+	// Function is either ScanScrub or ScanResilver
+	Function string
+
+	// State is one of ScanScanning, ScanFinished, ScanCanceled or ScanPaused
+	State string
+
+	StartTime time.Time
+	EndTime   time.Time
+	Errors    uint64
+
+	// PercentDone is the progress of running scan
+	PercentDone float64
+
+	// Text is the original description of the scan
+	Text string
+}
+
+// RemovalStatus is the status of device removal
+type RemovalStatus struct {
+	// State is one of ScanScanning, ScanFinished or ScanCanceled
+	State string
+
+	Copied      uint64
+	ToCopy      uint64
+	PercentDone float64
+
+	// Text is the original description of the removal
+	Text string
+}
+
+// PoolStatus is the status of ZPool
+type PoolStatus struct {
+	Name   string
+	GUID   uint64
+	State  string
+	Status string
+	Action string
+
+	// ErrorCount is the number of known data errors
+	ErrorCount uint64
+
+	Scan       *ScanStatus
+	Removal    *RemovalStatus
+	Checkpoint *CheckpointStatus
+
+	// Vdevs is the root of the tree of data vdevs
+	Vdevs *VdevStatus
+
+	Logs    []*VdevStatus
+	Special []*VdevStatus
+	Dedup   []*VdevStatus
+	Cache   []*VdevStatus
+	Spares  []*VdevStatus
+}
+
+// Status returns status of ZPool
+func (p *Pool) Status(ctx context.Context) (*PoolStatus, error) {
+	out, err := zpoolOutput(ctx, "status", "-j", "--json-int", "-p", "-P", p.Name)
+	switch {
+	case err == nil:
+		return parseStatusJSON(out, p.Name)
+	case !isUnsupportedOption(err):
+		return nil, err
+	}
+
+	// zpool older than 2.3 doesn't support JSON output
+	out, err = zpoolOutput(ctx, "status", "-p", "-P", p.Name)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := parseStatusText(out)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range statuses {
+		if status.Name == p.Name {
+			return status, nil
+		}
+	}
+	return nil, errors.Errorf("status of pool %q not found", p.Name)
+}
+
+// parseStatusText parses the output of zpool status
+func parseStatusText(out []byte) ([]*PoolStatus, error) {
+	var (
+		statuses []*PoolStatus
+		current  *PoolStatus
+		key      string
+		values   map[string][]string
+	)
+
+	finish := func() error {
+		if current == nil {
+			return nil
+		}
+		if err := current.fillFromText(values); err != nil {
+			return err
+		}
+		statuses = append(statuses, current)
+		return nil
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		if k, v, ok := parseStatusKey(line); ok {
+			if k == "pool" {
+				if err := finish(); err != nil {
+					return nil, err
+				}
+				current = &PoolStatus{}
+				values = map[string][]string{}
+			}
+			if current == nil {
+				return nil, errors.Errorf("unexpected line in pool status: %q", line)
+			}
+			key = k
+			values[key] = append(values[key], v)
+			continue
+		}
+		if current == nil || key == "" {
+			continue
+		}
+		values[key] = append(values[key], line)
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+var statusKeyRegexp = regexp.MustCompile(`^\s*([a-z]+): ?(.*)$`)
+
+func parseStatusKey(line string) (string, string, bool) {
+	if strings.HasPrefix(line, "\t") {
+		return "", "", false
+	}
+	m := statusKeyRegexp.FindStringSubmatch(line)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], strings.TrimSpace(m[2]), true
+}
+
+func (s *PoolStatus) fillFromText(values map[string][]string) error {
+	text := func(key string) string {
+		lines := values[key]
+		for i := range lines {
+			lines[i] = strings.TrimSpace(lines[i])
+		}
+		return strings.TrimSpace(strings.Join(lines, " "))
+	}
+
+	s.Name = text("pool")
+	if id := text("id"); id != "" {
+		if err := setUint(&s.GUID, id); err != nil {
+			return errors.Wrapf(err, "invalid pool id %q", id)
+		}
+	}
+	s.State = text("state")
+	s.Status = text("status")
+	s.Action = text("action")
+
+	if scan := text("scan"); scan != "" && scan != "none requested" {
+		s.Scan = parseScanText(scan)
+	}
+
+	if removal := text("remove"); removal != "" {
+		s.Removal = parseRemovalText(removal)
+	}
+
+	if checkpoint := text("checkpoint"); checkpoint != "" {
+		s.Checkpoint = parseCheckpointText(checkpoint)
+	}
+
+	if errText := text("errors"); errText != "" && errText != "No known data errors" {
+		count, _, _ := strings.Cut(errText, " ")
+		if err := setUint(&s.ErrorCount, count); err != nil {
+			return errors.Wrapf(err, "invalid errors summary %q", errText)
+		}
+	}
+
+	return s.fillConfigFromText(values["config"])
+}
+
+func (s *PoolStatus) fillConfigFromText(lines []string) error {
+	type level struct {
+		depth int
+		vdev  *VdevStatus
+	}
+
+	var (
+		stack []level
+		class *[]*VdevStatus
+	)
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "\t") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		line = strings.TrimPrefix(line, "\t")
+		fields := strings.Fields(line)
+		if fields[0] == "NAME" {
+			continue
+		}
+		depth := (len(line) - len(strings.TrimLeft(line, " "))) / 2
+
+		if depth == 0 {
+			stack = stack[:0]
+			switch fields[0] {
+			case "logs":
+				class = &s.Logs
+				continue
+			case "special":
+				class = &s.Special
+				continue
+			case "dedup":
+				class = &s.Dedup
+				continue
+			case "cache":
+				class = &s.Cache
+				continue
+			case "spares":
+				class = &s.Spares
+				continue
+			}
+		}
+
+		vdev, err := parseVdevText(fields)
+		if err != nil {
+			return err
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].depth >= depth {
+			stack = stack[:len(stack)-1]
+		}
+		switch {
+		case len(stack) > 0:
+			parent := stack[len(stack)-1].vdev
+			parent.Children = append(parent.Children, vdev)
+		case depth == 0:
+			s.Vdevs = vdev
+			class = nil
+		case class != nil:
+			*class = append(*class, vdev)
+		default:
+			return errors.Errorf("unexpected vdev line in pool status: %q", line)
+		}
+		stack = append(stack, level{depth: depth, vdev: vdev})
+	}
+	return nil
+}
+
+func parseVdevText(fields []string) (*VdevStatus, error) {
+	vdev := &VdevStatus{Name: fields[0]}
+	if len(fields) > 1 {
+		vdev.State = fields[1]
+	}
+	if len(fields) < 5 {
+		if len(fields) > 2 {
+			vdev.Message = strings.Join(fields[2:], " ")
+		}
+		return vdev, nil
+	}
+	if err := setUint(&vdev.ReadErrors, fields[2]); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := setUint(&vdev.WriteErrors, fields[3]); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := setUint(&vdev.ChecksumErrors, fields[4]); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	vdev.Message = strings.Join(fields[5:], " ")
+	return vdev, nil
+}
+
+var (
+	scanErrorsRegexp  = regexp.MustCompile(`with (\d+) errors`)
+	scanPercentRegexp = regexp.MustCompile(`([0-9.]+)% done`)
+	scanTimeRegexp    = regexp.MustCompile(`(?:since|on) ([A-Z][a-z]{2} [A-Z][a-z]{2} [ 0-9]{2} \d{2}:\d{2}:\d{2} \d{4})`)
+)
+
+const scanTimeLayout = "Mon Jan _2 15:04:05 2006"
+
+func parseScanText(text string) *ScanStatus {
+	scan := &ScanStatus{Text: text}
+
+	switch {
+	case strings.HasPrefix(text, "resilver"):
+		scan.Function = ScanResilver
+	default:
+		scan.Function = ScanScrub
+	}
+
+	switch {
+	case strings.Contains(text, "in progress since"):
+		scan.State = ScanScanning
+	case strings.Contains(text, "paused since"):
+		scan.State = ScanPaused
+	case strings.Contains(text, "canceled on"):
+		scan.State = ScanCanceled
+	default:
+		scan.State = ScanFinished
+	}
+
+	if m := scanTimeRegexp.FindStringSubmatch(text); m != nil {
+		if t, err := time.ParseInLocation(scanTimeLayout, m[1], time.Local); err == nil {
+			if scan.State == ScanScanning || scan.State == ScanPaused {
+				scan.StartTime = t
+			} else {
+				scan.EndTime = t
+			}
+		}
+	}
+	if m := scanErrorsRegexp.FindStringSubmatch(text); m != nil {
+		scan.Errors, _ = strconv.ParseUint(m[1], 10, 64)
+	}
+	if m := scanPercentRegexp.FindStringSubmatch(text); m != nil {
+		scan.PercentDone, _ = strconv.ParseFloat(m[1], 64)
+	}
+	if scan.State == ScanFinished {
+		scan.PercentDone = 100
+	}
+	return scan
+}
+
+var removalCopiedRegexp = regexp.MustCompile(`(\d+) copied out of (\d+)`)
+
+func parseRemovalText(text string) *RemovalStatus {
+	removal := &RemovalStatus{Text: text}
+	switch {
+	case strings.Contains(text, "in progress since"):
+		removal.State = ScanScanning
+	case strings.Contains(text, "canceled on"):
+		removal.State = ScanCanceled
+	default:
+		removal.State = ScanFinished
+		removal.PercentDone = 100
+	}
+	if m := removalCopiedRegexp.FindStringSubmatch(text); m != nil {
+		removal.Copied, _ = strconv.ParseUint(m[1], 10, 64)
+		removal.ToCopy, _ = strconv.ParseUint(m[2], 10, 64)
+	}
+	if m := scanPercentRegexp.FindStringSubmatch(text); m != nil {
+		removal.PercentDone, _ = strconv.ParseFloat(m[1], 64)
+	}
+	return removal
+}
+
+// jsonUint decodes unsigned integers encoded either as numbers or strings
+type jsonUint uint64
+
+func (u *jsonUint) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	if value == "" || value == "-" || value == "null" {
+		*u = 0
+		return nil
+	}
+	v, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	*u = jsonUint(v)
+	return nil
+}
+
+type jsonVdev struct {
+	Name           string    `json:"name"`
+	State          string    `json:"state"`
+	ReadErrors     jsonUint  `json:"read_errors"`
+	WriteErrors    jsonUint  `json:"write_errors"`
+	ChecksumErrors jsonUint  `json:"checksum_errors"`
+	Vdevs          jsonVdevs `json:"vdevs"`
+}
+
+// jsonVdevs decodes the object of vdevs preserving their order
+type jsonVdevs []jsonVdev
+
+func (v *jsonVdevs) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if _, err := decoder.Token(); err != nil {
+		return errors.WithStack(err)
+	}
+	for decoder.More() {
+		if _, err := decoder.Token(); err != nil {
+			return errors.WithStack(err)
+		}
+		var vdev jsonVdev
+		if err := decoder.Decode(&vdev); err != nil {
+			return errors.WithStack(err)
+		}
+		*v = append(*v, vdev)
+	}
+	return nil
+}
+
+func (v jsonVdev) status() *VdevStatus {
+	vdev := &VdevStatus{
+		Name:           v.Name,
+		State:          v.State,
+		ReadErrors:     uint64(v.ReadErrors),
+		WriteErrors:    uint64(v.WriteErrors),
+		ChecksumErrors: uint64(v.ChecksumErrors),
+	}
+	for _, child := range v.Vdevs {
+		vdev.Children = append(vdev.Children, child.status())
+	}
+	return vdev
+}
+
+func (v jsonVdevs) statuses() []*VdevStatus {
+	if len(v) == 0 {
+		return nil
+	}
+	vdevs := make([]*VdevStatus, 0, len(v))
+	for _, vdev := range v {
+		vdevs = append(vdevs, vdev.status())
+	}
+	return vdevs
+}
+
+type jsonScan struct {
+	Function   string   `json:"function"`
+	State      string   `json:"state"`
+	StartTime  jsonUint `json:"start_time"`
+	EndTime    jsonUint `json:"end_time"`
+	ToExamine  jsonUint `json:"to_examine"`
+	Examined   jsonUint `json:"examined"`
+	Errors     jsonUint `json:"errors"`
+	ScrubPause jsonUint `json:"scrub_pause"`
+}
+
+type jsonRemoval struct {
+	State  string   `json:"state"`
+	ToCopy jsonUint `json:"to_copy"`
+	Copied jsonUint `json:"copied"`
+}
+
+type jsonPool struct {
+	Name       string          `json:"name"`
+	GUID       jsonUint        `json:"pool_guid"`
+	State      string          `json:"state"`
+	Status     string          `json:"status"`
+	Action     string          `json:"action"`
+	ErrorCount jsonUint        `json:"error_count"`
+	ScanStats  *jsonScan       `json:"scan_stats"`
+	Removal    *jsonRemoval    `json:"removal_stats"`
+	Checkpoint *jsonCheckpoint `json:"checkpoint_stats"`
+	Vdevs      jsonVdevs       `json:"vdevs"`
+	Logs       jsonVdevs       `json:"logs"`
+	Special    jsonVdevs       `json:"special"`
+	Dedup      jsonVdevs       `json:"dedup"`
+	Cache      jsonVdevs       `json:"l2cache"`
+	Spares     jsonVdevs       `json:"spares"`
+}
+
+// parseStatusJSON parses the output of zpool status -j --json-int
+func parseStatusJSON(out []byte, name string) (*PoolStatus, error) {
+	var result struct {
+		Pools map[string]jsonPool `json:"pools"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	pool, exists := result.Pools[name]
+	if !exists {
+		return nil, errors.Errorf("status of pool %q not found", name)
+	}
+
+	status := &PoolStatus{
+		Name:       pool.Name,
+		GUID:       uint64(pool.GUID),
+		State:      pool.State,
+		Status:     pool.Status,
+		Action:     pool.Action,
+		ErrorCount: uint64(pool.ErrorCount),
+		Logs:       pool.Logs.statuses(),
+		Special:    pool.Special.statuses(),
+		Dedup:      pool.Dedup.statuses(),
+		Cache:      pool.Cache.statuses(),
+		Spares:     pool.Spares.statuses(),
+	}
+	if len(pool.Vdevs) > 0 {
+		status.Vdevs = pool.Vdevs[0].status()
+	}
+	if pool.ScanStats != nil && pool.ScanStats.Function != "" && pool.ScanStats.Function != "NONE" {
+		status.Scan = pool.ScanStats.status()
+	}
+	if pool.Removal != nil && pool.Removal.State != "" && pool.Removal.State != "NONE" {
+		status.Removal = pool.Removal.status()
+	}
+	if pool.Checkpoint != nil && pool.Checkpoint.State != "" && pool.Checkpoint.State != "NONE" {
+		status.Checkpoint = pool.Checkpoint.status()
+	}
+	return status, nil
+}
+
+func (r jsonRemoval) status() *RemovalStatus {
+	removal := &RemovalStatus{
+		State:  strings.ToLower(r.State),
+		Copied: uint64(r.Copied),
+		ToCopy: uint64(r.ToCopy),
+	}
+	switch {
+	case removal.State == ScanFinished:
+		removal.PercentDone = 100
+	case r.ToCopy > 0:
+		removal.PercentDone = 100 * float64(r.Copied) / float64(r.ToCopy)
+	}
+	return removal
+}
+
+func (s jsonScan) status() *ScanStatus {
+	scan := &ScanStatus{
+		Function: strings.ToLower(s.Function),
+		State:    strings.ToLower(s.State),
+		Errors:   uint64(s.Errors),
+	}
+	if scan.State == "canceled" {
+		scan.State = ScanCanceled
+	}
+	if scan.State == ScanScanning && s.ScrubPause > 0 {
+		scan.State = ScanPaused
+	}
+	if s.StartTime > 0 {
+		scan.StartTime = time.Unix(int64(s.StartTime), 0)
+	}
+	if s.EndTime > 0 {
+		scan.EndTime = time.Unix(int64(s.EndTime), 0)
+	}
+	switch {
+	case scan.State == ScanFinished:
+		scan.PercentDone = 100
+	case s.ToExamine > 0:
+		scan.PercentDone = 100 * float64(s.Examined) / float64(s.ToExamine)
+	}
+	return scan
+}
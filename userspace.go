@@ -0,0 +1,97 @@
+package zfs
+
+import (
+	"context"
+	"strconv"
+)
+
+// SpaceUsage is the space consumed by single user, group or project
+type SpaceUsage struct {
+	// Type is the type of the entity, e.g. "POSIX User", "POSIX Group" or "Project"
+	Type string
+
+	// Name is the name of the user or group or the ID of the project
+	Name string
+
+	Used     uint64
+	Quota    uint64
+	ObjUsed  uint64
+	ObjQuota uint64
+}
+
+// UserSpace returns space consumed by each user in the filesystem
+func (d *Filesystem) UserSpace(ctx context.Context) ([]SpaceUsage, error) {
+	return spaceUsage(ctx, "userspace", d.Info.Name)
+}
+
+// GroupSpace returns space consumed by each group in the filesystem
+func (d *Filesystem) GroupSpace(ctx context.Context) ([]SpaceUsage, error) {
+	return spaceUsage(ctx, "groupspace", d.Info.Name)
+}
+
+// ProjectSpace returns space consumed by each project in the filesystem
+func (d *Filesystem) ProjectSpace(ctx context.Context) ([]SpaceUsage, error) {
+	return spaceUsage(ctx, "projectspace", d.Info.Name)
+}
+
+// SetUserQuota sets the space quota of the user, 0 removes the quota
+func (d *Filesystem) SetUserQuota(ctx context.Context, user string, quota uint64) error {
+	return setQuota(ctx, d.Info.Name, "userquota@"+user, quota)
+}
+
+// SetGroupQuota sets the space quota of the group, 0 removes the quota
+func (d *Filesystem) SetGroupQuota(ctx context.Context, group string, quota uint64) error {
+	return setQuota(ctx, d.Info.Name, "groupquota@"+group, quota)
+}
+
+// SetUserObjQuota sets the object count quota of the user, 0 removes the quota
+func (d *Filesystem) SetUserObjQuota(ctx context.Context, user string, quota uint64) error {
+	return setQuota(ctx, d.Info.Name, "userobjquota@"+user, quota)
+}
+
+// SetProjectQuota sets the space quota of the project, 0 removes the quota
+func (d *Filesystem) SetProjectQuota(ctx context.Context, project uint64, quota uint64) error {
+	return setQuota(ctx, d.Info.Name, "projectquota@"+strconv.FormatUint(project, 10), quota)
+}
+
+func setQuota(ctx context.Context, name, key string, quota uint64) error {
+	value := "none"
+	if quota > 0 {
+		value = strconv.FormatUint(quota, 10)
+	}
+	return setProperty(ctx, name, key, value)
+}
+
+func spaceUsage(ctx context.Context, cmd, name string) ([]SpaceUsage, error) {
+	out, err := zfs(ctx, cmd, "-H", "-p", "-o", "type,name,used,quota,objused,objquota", name)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make([]SpaceUsage, 0, len(out))
+	for _, line := range out {
+		u := SpaceUsage{Type: line[0], Name: line[1]}
+		if err := setQuotaUint(&u.Used, line[2]); err != nil {
+			return nil, err
+		}
+		if err := setQuotaUint(&u.Quota, line[3]); err != nil {
+			return nil, err
+		}
+		if err := setQuotaUint(&u.ObjUsed, line[4]); err != nil {
+			return nil, err
+		}
+		if err := setQuotaUint(&u.ObjQuota, line[5]); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, nil
+}
+
+func setQuotaUint(field *uint64, value string) error {
+	if value == "none" {
+		*field = 0
+		return nil
+	}
+	return setUint(field, value)
+}
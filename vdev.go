@@ -0,0 +1,220 @@
+package zfs
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// VdevType is the type of virtual device
+type VdevType string
+
+// Vdev types
+const (
+	VdevStripe VdevType = ""
+	VdevMirror VdevType = "mirror"
+	VdevRaidZ1 VdevType = "raidz1"
+	VdevRaidZ2 VdevType = "raidz2"
+	VdevRaidZ3 VdevType = "raidz3"
+	VdevDRaid  VdevType = "draid"
+)
+
+// DRaidConfig configures distributed spare RAID, zero values mean defaults
+type DRaidConfig struct {
+	Parity   uint
+	Data     uint
+	Children uint
+	Spares   uint
+}
+
+// Vdev is the specification of virtual device
+type Vdev struct {
+	Type    VdevType
+	Devices []string
+
+	// DRaid is used only by VdevDRaid
+	DRaid DRaidConfig
+}
+
+// Stripe returns the specification of devices used directly without redundancy
+func Stripe(devices ...string) Vdev {
+	return Vdev{Type: VdevStripe, Devices: devices}
+}
+
+// Mirror returns the specification of mirror vdev
+func Mirror(devices ...string) Vdev {
+	return Vdev{Type: VdevMirror, Devices: devices}
+}
+
+// RaidZ returns the specification of raidz vdev with parity 1, 2 or 3
+func RaidZ(parity uint, devices ...string) Vdev {
+	return Vdev{Type: VdevType("raidz" + strconv.FormatUint(uint64(parity), 10)), Devices: devices}
+}
+
+// DRaid returns the specification of draid vdev
+func DRaid(config DRaidConfig, devices ...string) Vdev {
+	return Vdev{Type: VdevDRaid, Devices: devices, DRaid: config}
+}
+
+func (v Vdev) args() ([]string, error) {
+	if len(v.Devices) == 0 {
+		return nil, errors.New("vdev has no devices")
+	}
+
+	args := make([]string, 0, len(v.Devices)+1)
+	switch v.Type {
+	case VdevStripe:
+	case VdevMirror, VdevRaidZ1, VdevRaidZ2, VdevRaidZ3:
+		args = append(args, string(v.Type))
+	case VdevDRaid:
+		spec := string(VdevDRaid)
+		if v.DRaid.Parity > 0 {
+			spec += strconv.FormatUint(uint64(v.DRaid.Parity), 10)
+		}
+		if v.DRaid.Data > 0 {
+			spec += ":" + strconv.FormatUint(uint64(v.DRaid.Data), 10) + "d"
+		}
+		if v.DRaid.Children > 0 {
+			spec += ":" + strconv.FormatUint(uint64(v.DRaid.Children), 10) + "c"
+		}
+		if v.DRaid.Spares > 0 {
+			spec += ":" + strconv.FormatUint(uint64(v.DRaid.Spares), 10) + "s"
+		}
+		args = append(args, spec)
+	default:
+		return nil, errors.Errorf("unknown vdev type %q", v.Type)
+	}
+	return append(args, v.Devices...), nil
+}
+
+// PoolLayout describes the virtual devices of a pool
+type PoolLayout struct {
+	Data    []Vdev
+	Log     []Vdev
+	Special []Vdev
+	Dedup   []Vdev
+	Cache   []string
+	Spare   []string
+}
+
+func (l PoolLayout) args() ([]string, error) {
+	args := []string{}
+	for _, class := range []struct {
+		Name  string
+		Vdevs []Vdev
+	}{
+		{Vdevs: l.Data},
+		{Name: "log", Vdevs: l.Log},
+		{Name: "special", Vdevs: l.Special},
+		{Name: "dedup", Vdevs: l.Dedup},
+	} {
+		if len(class.Vdevs) == 0 {
+			continue
+		}
+		if class.Name != "" {
+			args = append(args, class.Name)
+		}
+		for _, v := range class.Vdevs {
+			vArgs, err := v.args()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, vArgs...)
+		}
+	}
+	if len(l.Cache) > 0 {
+		args = append(append(args, "cache"), l.Cache...)
+	}
+	if len(l.Spare) > 0 {
+		args = append(append(args, "spare"), l.Spare...)
+	}
+	if len(args) == 0 {
+		return nil, errors.New("pool layout is empty")
+	}
+	return args, nil
+}
+
+// Add adds virtual devices to ZPool
+func (p *Pool) Add(ctx context.Context, layout PoolLayout, force bool) (*PoolStatus, error) {
+	vdevs, err := layout.args()
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"add"}
+	if force {
+		args = append(args, "-f")
+	}
+	return p.vdevCommand(ctx, append(append(args, p.Name), vdevs...)...)
+}
+
+// Remove starts removal of the device from ZPool, data are evacuated to other devices in background.
+// Progress of evacuation is reported by Removal field of pool status.
+func (p *Pool) Remove(ctx context.Context, device string) (*PoolStatus, error) {
+	return p.vdevCommand(ctx, "remove", p.Name, device)
+}
+
+// CancelRemove cancels running removal of device
+func (p *Pool) CancelRemove(ctx context.Context) (*PoolStatus, error) {
+	return p.vdevCommand(ctx, "remove", "-s", p.Name)
+}
+
+// Attach attaches new device to the existing one creating or extending mirror.
+// Unlike Add it takes plain device paths instead of Vdev specifications, because zpool attaches
+// single leaf devices only, type of the resulting vdev is determined by the existing device.
+func (p *Pool) Attach(ctx context.Context, device, newDevice string, force bool) (*PoolStatus, error) {
+	args := []string{"attach"}
+	if force {
+		args = append(args, "-f")
+	}
+	return p.vdevCommand(ctx, append(args, p.Name, device, newDevice)...)
+}
+
+// Detach detaches device from mirror
+func (p *Pool) Detach(ctx context.Context, device string) (*PoolStatus, error) {
+	return p.vdevCommand(ctx, "detach", p.Name, device)
+}
+
+// Replace replaces device with the new one, if newDevice is empty device is replaced in place.
+// Like Attach it takes plain device paths, because only single leaf device might be replaced.
+func (p *Pool) Replace(ctx context.Context, device, newDevice string, force bool) (*PoolStatus, error) {
+	args := []string{"replace"}
+	if force {
+		args = append(args, "-f")
+	}
+	args = append(args, p.Name, device)
+	if newDevice != "" {
+		args = append(args, newDevice)
+	}
+	return p.vdevCommand(ctx, args...)
+}
+
+// Online brings devices online, if expand is set devices are expanded to use all the available space
+func (p *Pool) Online(ctx context.Context, expand bool, devices ...string) (*PoolStatus, error) {
+	args := []string{"online"}
+	if expand {
+		args = append(args, "-e")
+	}
+	return p.vdevCommand(ctx, append(append(args, p.Name), devices...)...)
+}
+
+// Offline takes devices offline, if temporary is set devices are brought back online on reboot
+func (p *Pool) Offline(ctx context.Context, temporary bool, devices ...string) (*PoolStatus, error) {
+	args := []string{"offline"}
+	if temporary {
+		args = append(args, "-t")
+	}
+	return p.vdevCommand(ctx, append(append(args, p.Name), devices...)...)
+}
+
+// Clear clears device errors, errors of all the devices are cleared if none is specified
+func (p *Pool) Clear(ctx context.Context, devices ...string) (*PoolStatus, error) {
+	return p.vdevCommand(ctx, append([]string{"clear", p.Name}, devices...)...)
+}
+
+func (p *Pool) vdevCommand(ctx context.Context, args ...string) (*PoolStatus, error) {
+	if _, err := zpool(ctx, args...); err != nil {
+		return nil, err
+	}
+	return p.Status(ctx)
+}
@@ -0,0 +1,36 @@
+package zfs
+
+import (
+	"context"
+	"math"
+)
+
+const datasetVolume = "volume"
+
+// Volumes returns a slice of ZFS volumes
+func Volumes(ctx context.Context) ([]*Volume, error) {
+	infos, err := info(ctx, datasetVolume, "", math.MaxUint16)
+	if err != nil {
+		return nil, err
+	}
+	volumes := []*Volume{}
+	for _, info := range infos {
+		volumes = append(volumes, &Volume{Info: info})
+	}
+	return volumes, nil
+}
+
+// GetVolume retrieves a single ZFS volume by name
+func GetVolume(ctx context.Context, name string) (*Volume, error) {
+	info, err := info(ctx, datasetVolume, name, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Volume{Info: info[0]}, nil
+}
+
+// Volume is a ZFS volume
+type Volume struct {
+	Info Info
+}
@@ -1,6 +1,7 @@
 package zfs
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"fmt"
@@ -8,11 +9,14 @@ import (
 	"os/exec"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/outofforest/libexec"
+	"github.com/outofforest/parallel"
+	"github.com/pkg/errors"
 )
 
-var dsPropListOptions = strings.Join([]string{"name", "origin", "used", "available", "mountpoint", "compression", "volsize", "quota", "referenced", "written", "logicalused", "usedbydataset"}, ",")
+var dsPropListOptions = strings.Join([]string{"name", "origin", "used", "available", "mountpoint", "compression", "volsize", "quota", "referenced", "written", "logicalused", "usedbydataset", "creation"}, ",")
 
 type cmdError struct {
 	Err    error
@@ -24,6 +28,20 @@ func (e cmdError) Error() string {
 	return fmt.Sprintf("%s => %s", e.Err, e.Stderr)
 }
 
+// IsNotExist reports if command failed because dataset or pool does not exist
+func IsNotExist(err error) bool {
+	var cmdErr *cmdError
+	return errors.As(err, &cmdErr) &&
+		(strings.Contains(cmdErr.Stderr, "does not exist") || strings.Contains(cmdErr.Stderr, "no such pool"))
+}
+
+// isUnsupportedOption reports if command failed because installed tool doesn't recognize the option
+func isUnsupportedOption(err error) bool {
+	var cmdErr *cmdError
+	return errors.As(err, &cmdErr) &&
+		(strings.Contains(cmdErr.Stderr, "invalid option") || strings.Contains(cmdErr.Stderr, "unrecognized option"))
+}
+
 func setString(field *string, value string) {
 	v := ""
 	if value != "-" {
@@ -59,6 +77,7 @@ type Info struct {
 	Usedbydataset uint64
 	Quota         uint64
 	Referenced    uint64
+	Creation      time.Time
 }
 
 func info(ctx context.Context, t, filter string, depth uint16) ([]Info, error) {
@@ -115,7 +134,18 @@ func parseLine(line []string, info *Info) error {
 	if err = setUint(&info.Logicalused, line[10]); err != nil {
 		return err
 	}
-	return setUint(&info.Usedbydataset, line[11])
+	if err = setUint(&info.Usedbydataset, line[11]); err != nil {
+		return err
+	}
+
+	var creation uint64
+	if err = setUint(&creation, line[12]); err != nil {
+		return err
+	}
+	if creation > 0 {
+		info.Creation = time.Unix(int64(creation), 0)
+	}
+	return nil
 }
 
 func propsSlice(properties map[string]string) []string {
@@ -146,40 +176,107 @@ func zfs(ctx context.Context, args ...string) ([][]string, error) {
 
 func zfsStdin(ctx context.Context, stdin io.Reader, args ...string) ([][]string, error) {
 	sOut := &bytes.Buffer{}
-	sErr := &bytes.Buffer{}
-	cmd := exec.Command("zfs", args...)
-	cmd.Stdout = sOut
-	cmd.Stderr = sErr
-	cmd.Stdin = stdin
-	if err := libexec.Exec(ctx, cmd); err != nil {
-		return nil, &cmdError{Err: err, Stderr: sErr.String()}
+	if err := run(ctx, "zfs", stdin, sOut, args...); err != nil {
+		return nil, err
 	}
 
 	return outputToFields(sOut.String()), nil
 }
 
 func zfsStdout(ctx context.Context, stdout io.Writer, args ...string) error {
-	sErr := &bytes.Buffer{}
-	cmd := exec.Command("zfs", args...)
-	cmd.Stdout = stdout
-	cmd.Stderr = sErr
-	if err := libexec.Exec(ctx, cmd); err != nil {
-		return &cmdError{Err: err, Stderr: sErr.String()}
-	}
-	return nil
+	return run(ctx, "zfs", nil, stdout, args...)
+}
+
+// zfsStream runs zfs command and passes each line of its output to the callback as it arrives.
+func zfsStream(ctx context.Context, args []string, fn func(line []string) error) error {
+	return streamLines(ctx, func(ctx context.Context, stdout io.Writer) error {
+		return zfsStdout(ctx, stdout, args...)
+	}, fn)
+}
+
+func streamLines(ctx context.Context, run func(ctx context.Context, stdout io.Writer) error,
+	fn func(line []string) error,
+) error {
+	r, w := io.Pipe()
+	return parallel.Run(ctx, func(ctx context.Context, spawn parallel.SpawnFn) error {
+		spawn("cmd", parallel.Continue, func(ctx context.Context) error {
+			err := run(ctx, w)
+			_ = w.CloseWithError(err)
+			return err
+		})
+		spawn("parser", parallel.Exit, func(ctx context.Context) error {
+			defer r.Close()
+
+			scanner := bufio.NewScanner(r)
+			scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+			for scanner.Scan() {
+				if err := fn(strings.Split(scanner.Text(), "\t")); err != nil {
+					return err
+				}
+			}
+			return errors.WithStack(scanner.Err())
+		})
+		return nil
+	})
 }
 
 func zpool(ctx context.Context, args ...string) ([][]string, error) {
+	out, err := zpoolOutput(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return outputToFields(string(out)), nil
+}
+
+func zpoolOutput(ctx context.Context, args ...string) ([]byte, error) {
 	sOut := &bytes.Buffer{}
+	if err := run(ctx, "zpool", nil, sOut, args...); err != nil {
+		return nil, err
+	}
+
+	return sOut.Bytes(), nil
+}
+
+func zpoolStdout(ctx context.Context, stdout io.Writer, args ...string) error {
+	return run(ctx, "zpool", nil, stdout, args...)
+}
+
+// run executes the command and reports its invocation to the hooks stored in context.
+func run(ctx context.Context, name string, stdin io.Reader, stdout io.Writer, args ...string) error {
 	sErr := &bytes.Buffer{}
-	cmd := exec.Command("zpool", args...)
-	cmd.Stdout = sOut
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
 	cmd.Stderr = sErr
-	if err := libexec.Exec(ctx, cmd); err != nil {
-		return nil, &cmdError{Err: err, Stderr: sErr.String()}
+
+	hooks := hooksFromContext(ctx)
+	info := &CommandInfo{
+		Command: name,
+		Args:    append([]string{}, args...),
+		Stdin:   stdin != nil,
+		Start:   time.Now(),
+	}
+	hookCtxs := make([]context.Context, len(hooks))
+	for i, h := range hooks {
+		hookCtxs[i] = h.Before(ctx, info)
 	}
 
-	return outputToFields(sOut.String()), nil
+	var err error
+	if execErr := libexec.Exec(ctx, cmd); execErr != nil {
+		err = &cmdError{Err: execErr, Stderr: sErr.String()}
+	}
+
+	info.Duration = time.Since(info.Start)
+	info.ExitCode = -1
+	if cmd.ProcessState != nil {
+		info.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	info.Stderr = sErr.String()
+	info.Err = err
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i].After(hookCtxs[i], info)
+	}
+	return err
 }
 
 func outputToFields(out string) [][]string {
@@ -197,7 +294,13 @@ func outputToFields(out string) [][]string {
 }
 
 func destroy(ctx context.Context, name string, flags DestroyFlag) error {
-	args := make([]string, 1, 3)
+	args := append(destroyArgs(flags), name)
+	_, err := zfs(ctx, args...)
+	return err
+}
+
+func destroyArgs(flags DestroyFlag) []string {
+	args := make([]string, 1, 6)
 	args[0] = "destroy"
 	if flags&DestroyRecursive != 0 {
 		args = append(args, "-r")
@@ -214,10 +317,7 @@ func destroy(ctx context.Context, name string, flags DestroyFlag) error {
 	if flags&DestroyForceUmount != 0 {
 		args = append(args, "-f")
 	}
-
-	args = append(args, name)
-	_, err := zfs(ctx, args...)
-	return err
+	return args
 }
 
 func setProperty(ctx context.Context, name, key, val string) error {
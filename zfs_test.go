@@ -5,15 +5,32 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/outofforest/logger"
 	"github.com/outofforest/parallel"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+type recordingHook struct {
+	before int
+	infos  []CommandInfo
+}
+
+func (h *recordingHook) Before(ctx context.Context, info *CommandInfo) context.Context {
+	h.before++
+	return ctx
+}
+
+func (h *recordingHook) After(ctx context.Context, info *CommandInfo) {
+	h.infos = append(h.infos, *info)
+}
+
 type testCase struct {
 	Name string
 	Fn   func(t *testing.T, ctx context.Context)
@@ -35,6 +52,38 @@ var zfsTests = []testCase{
 			assert.Error(t, err)
 		},
 	},
+	{
+		Name: "TestCreateFilesystemOptions",
+		Fn: func(t *testing.T, ctx context.Context) {
+			const name = "gozfs/a/b/fs"
+
+			_, err := CreateFilesystem(ctx, name, CreateFilesystemOptions{})
+			require.Error(t, err)
+
+			plan, err := CreateFilesystemDryRun(ctx, name, CreateFilesystemOptions{
+				Properties: map[string]string{"test:prop": "value"},
+				Parents:    true,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, CreateFilesystemPlan{
+				Name:       name,
+				Properties: map[string]string{"test:prop": "value"},
+			}, plan)
+			_, err = GetFilesystem(ctx, name)
+			require.Error(t, err)
+
+			fs, err := CreateFilesystem(ctx, name, CreateFilesystemOptions{Parents: true, NoMount: true})
+			require.NoError(t, err)
+			assert.Equal(t, name, fs.Info.Name)
+
+			mounted, err := fs.IsMounted(ctx)
+			require.NoError(t, err)
+			assert.False(t, mounted)
+
+			_, err = GetFilesystem(ctx, "gozfs/a/b")
+			require.NoError(t, err)
+		},
+	},
 	{
 		Name: "TestFilesystemProperties",
 		Fn: func(t *testing.T, ctx context.Context) {
@@ -84,12 +133,190 @@ var zfsTests = []testCase{
 			s, err := fs.Snapshot(ctx, sName)
 			require.NoError(t, err)
 			assert.Equal(t, fsName+"@"+sName, s.Info.Name)
+			assert.WithinDuration(t, time.Now(), s.Info.Creation, time.Minute)
 
 			require.NoError(t, s.Destroy(ctx, DestroyDefault))
 			_, err = GetSnapshot(ctx, fsName+"@"+sName)
 			assert.Error(t, err)
 		},
 	},
+	{
+		Name: "TestDestroySnapshots",
+		Fn: func(t *testing.T, ctx context.Context) {
+			fs, err := CreateFilesystem(ctx, "gozfs/fs", CreateFilesystemOptions{})
+			require.NoError(t, err)
+
+			for _, name := range []string{"1", "2", "3", "4", "5", "6"} {
+				_, err := fs.Snapshot(ctx, name)
+				require.NoError(t, err)
+			}
+
+			result, err := fs.DestroySnapshots(ctx, "2", "4", DestroyDefault)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"gozfs/fs@2", "gozfs/fs@3", "gozfs/fs@4"}, result.Snapshots)
+
+			result, err = fs.DestroySnapshotsByName(ctx, []string{"1", "6"}, DestroyDefault)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"gozfs/fs@1", "gozfs/fs@6"}, result.Snapshots)
+
+			ss, err := fs.Snapshots(ctx)
+			require.NoError(t, err)
+			require.Len(t, ss, 1)
+			assert.Equal(t, "gozfs/fs@5", ss[0].Info.Name)
+
+			result, err = fs.DestroySnapshots(ctx, "", "", DestroyDefault)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"gozfs/fs@5"}, result.Snapshots)
+
+			ss, err = fs.Snapshots(ctx)
+			require.NoError(t, err)
+			require.Len(t, ss, 0)
+		},
+	},
+	{
+		Name: "TestDelegation",
+		Fn: func(t *testing.T, ctx context.Context) {
+			parent, err := GetFilesystem(ctx, "gozfs")
+			require.NoError(t, err)
+			fs, err := CreateFilesystem(ctx, "gozfs/fs", CreateFilesystemOptions{})
+			require.NoError(t, err)
+
+			require.NoError(t, parent.Allow(ctx, Delegation{
+				Target:      DelegateToEveryone,
+				Permissions: []string{"mount"},
+				Descendent:  true,
+			}))
+			require.NoError(t, fs.Allow(ctx, Delegation{
+				Target:      DelegateToSet,
+				Names:       []string{"@tenant"},
+				Permissions: []string{"snapshot", "mount"},
+			}))
+			require.NoError(t, fs.Allow(ctx, Delegation{
+				Target:      DelegateToCreateTime,
+				Permissions: []string{"destroy"},
+			}))
+			require.NoError(t, fs.Allow(ctx, Delegation{
+				Target:      DelegateToUser,
+				Names:       []string{"root"},
+				Permissions: []string{"@tenant"},
+				Local:       true,
+			}))
+			require.NoError(t, fs.Allow(ctx, Delegation{
+				Target:      DelegateToGroup,
+				Names:       []string{"root"},
+				Permissions: []string{"create"},
+			}))
+
+			perms, err := fs.Permissions(ctx)
+			require.NoError(t, err)
+			require.Len(t, perms, 2)
+			assert.Equal(t, DatasetPermissions{
+				Dataset:    "gozfs/fs",
+				Sets:       map[string][]string{"@tenant": {"mount", "snapshot"}},
+				CreateTime: []string{"destroy"},
+				Local: []Permission{
+					{Target: DelegateToUser, Name: "root", Permissions: []string{"@tenant"}},
+				},
+				LocalDescendent: []Permission{
+					{Target: DelegateToGroup, Name: "root", Permissions: []string{"create"}},
+				},
+			}, perms[0])
+			assert.Equal(t, DatasetPermissions{
+				Dataset: "gozfs",
+				Sets:    map[string][]string{},
+				Descendent: []Permission{
+					{Target: DelegateToEveryone, Permissions: []string{"mount"}},
+				},
+			}, perms[1])
+
+			require.NoError(t, fs.Unallow(ctx, Delegation{Target: DelegateToUser, Names: []string{"root"}}, false))
+			require.NoError(t, parent.Unallow(ctx, Delegation{Target: DelegateToEveryone}, true))
+
+			perms, err = fs.Permissions(ctx)
+			require.NoError(t, err)
+			require.Len(t, perms, 1)
+			assert.Empty(t, perms[0].Local)
+			assert.Len(t, perms[0].LocalDescendent, 1)
+		},
+	},
+	{
+		Name: "TestVolumes",
+		Fn: func(t *testing.T, ctx context.Context) {
+			volumes, err := Volumes(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, volumes)
+
+			require.NoError(t, exec.Command("zfs", "create", "-V", "16M", "gozfs/vol").Run())
+
+			volumes, err = Volumes(ctx)
+			require.NoError(t, err)
+			require.Len(t, volumes, 1)
+			assert.Equal(t, "gozfs/vol", volumes[0].Info.Name)
+			assert.EqualValues(t, 16*1024*1024, volumes[0].Info.Volsize)
+
+			volume, err := GetVolume(ctx, "gozfs/vol")
+			require.NoError(t, err)
+			assert.Equal(t, volumes[0].Info, volume.Info)
+
+			_, err = GetVolume(ctx, "gozfs")
+			require.Error(t, err)
+		},
+	},
+	{
+		Name: "TestSpaceAccounting",
+		Fn: func(t *testing.T, ctx context.Context) {
+			fs, err := CreateFilesystem(ctx, "gozfs/fs", CreateFilesystemOptions{})
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile("/gozfs/fs/content", make([]byte, 1024*1024), 0o600))
+			require.NoError(t, exec.Command("sync").Run())
+
+			require.NoError(t, fs.SetUserQuota(ctx, "root", 10*1024*1024))
+			require.NoError(t, fs.SetGroupQuota(ctx, "root", 20*1024*1024))
+			require.NoError(t, fs.SetUserObjQuota(ctx, "root", 1000))
+			require.NoError(t, fs.SetProjectQuota(ctx, 10, 30*1024*1024))
+
+			quota, exists, err := fs.GetProperty(ctx, "projectquota@10")
+			require.NoError(t, err)
+			assert.True(t, exists)
+			assert.Equal(t, "30M", quota)
+
+			users, err := fs.UserSpace(ctx)
+			require.NoError(t, err)
+			require.Len(t, users, 1)
+			assert.Equal(t, "POSIX User", users[0].Type)
+			assert.Equal(t, "root", users[0].Name)
+			assert.NotZero(t, users[0].Used)
+			assert.EqualValues(t, 10*1024*1024, users[0].Quota)
+			assert.NotZero(t, users[0].ObjUsed)
+			assert.EqualValues(t, 1000, users[0].ObjQuota)
+
+			groups, err := fs.GroupSpace(ctx)
+			require.NoError(t, err)
+			require.Len(t, groups, 1)
+			assert.Equal(t, "POSIX Group", groups[0].Type)
+			assert.Equal(t, "root", groups[0].Name)
+			assert.EqualValues(t, 20*1024*1024, groups[0].Quota)
+			assert.Zero(t, groups[0].ObjQuota)
+
+			projects, err := fs.ProjectSpace(ctx)
+			require.NoError(t, err)
+			var project *SpaceUsage
+			for i := range projects {
+				if projects[i].Name == "10" {
+					project = &projects[i]
+				}
+			}
+			require.NotNil(t, project)
+			assert.Zero(t, project.Used)
+			assert.EqualValues(t, 30*1024*1024, project.Quota)
+
+			require.NoError(t, fs.SetUserQuota(ctx, "root", 0))
+			users, err = fs.UserSpace(ctx)
+			require.NoError(t, err)
+			require.Len(t, users, 1)
+			assert.Zero(t, users[0].Quota)
+		},
+	},
 	{
 		Name: "TestSnapshotProperties",
 		Fn: func(t *testing.T, ctx context.Context) {
@@ -365,6 +592,45 @@ var zfsTests = []testCase{
 			assert.Equal(t, "test", string(content))
 		},
 	},
+	{
+		Name: "TestMountOptions",
+		Fn: func(t *testing.T, ctx context.Context) {
+			fs, err := CreateFilesystem(ctx, "gozfs/fs", CreateFilesystemOptions{})
+			require.NoError(t, err)
+			child, err := CreateFilesystem(ctx, "gozfs/fs/child", CreateFilesystemOptions{})
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile("/gozfs/fs/child/content", []byte("test"), 0o600))
+
+			require.NoError(t, fs.UnmountWithOptions(ctx, UnmountOptions{Recursive: true}))
+			mounted, err := fs.IsMounted(ctx)
+			require.NoError(t, err)
+			assert.False(t, mounted)
+			mounted, err = child.IsMounted(ctx)
+			require.NoError(t, err)
+			assert.False(t, mounted)
+
+			require.NoError(t, fs.Mount(ctx))
+			require.NoError(t, child.MountWithOptions(ctx, MountOptions{Options: []string{"ro"}}))
+			mounted, err = child.IsMounted(ctx)
+			require.NoError(t, err)
+			assert.True(t, mounted)
+			content, err := os.ReadFile("/gozfs/fs/child/content")
+			require.NoError(t, err)
+			assert.Equal(t, "test", string(content))
+			assert.Error(t, os.WriteFile("/gozfs/fs/child/content", []byte("test2"), 0o600))
+
+			require.NoError(t, child.UnmountWithOptions(ctx, UnmountOptions{Force: true}))
+			require.NoError(t, fs.Unmount(ctx))
+			require.NoError(t, MountAll(ctx, MountOptions{}))
+			mounted, err = fs.IsMounted(ctx)
+			require.NoError(t, err)
+			assert.True(t, mounted)
+			mounted, err = child.IsMounted(ctx)
+			require.NoError(t, err)
+			assert.True(t, mounted)
+			require.NoError(t, os.WriteFile("/gozfs/fs/child/content", []byte("test2"), 0o600))
+		},
+	},
 	{
 		Name: "TestEncryption",
 		Fn: func(t *testing.T, ctx context.Context) {
@@ -387,6 +653,203 @@ var zfsTests = []testCase{
 			assert.Equal(t, "test", string(content))
 		},
 	},
+	{
+		Name: "TestHooks",
+		Fn: func(t *testing.T, ctx context.Context) {
+			hook := &recordingHook{}
+			ctx = WithHooks(ctx, LogHook{}, hook)
+
+			_, err := CreateFilesystem(ctx, "gozfs/fs", CreateFilesystemOptions{Password: "password"})
+			require.NoError(t, err)
+			_, err = GetFilesystem(ctx, "gozfs/missing")
+			require.Error(t, err)
+
+			require.Len(t, hook.infos, 3)
+			assert.Equal(t, "zfs", hook.infos[0].Command)
+			assert.Equal(t, "create", hook.infos[0].Args[0])
+			assert.True(t, hook.infos[0].Stdin)
+			assert.NotContains(t, hook.infos[0].Args, "password")
+			assert.Zero(t, hook.infos[0].ExitCode)
+			assert.NoError(t, hook.infos[0].Err)
+
+			failed := hook.infos[2]
+			assert.Equal(t, []string{"list", "-Hp", "-t", "filesystem", "-o", dsPropListOptions, "-d", "0",
+				"gozfs/missing"}, failed.Args)
+			assert.False(t, failed.Stdin)
+			assert.NotZero(t, failed.ExitCode)
+			assert.NotEmpty(t, failed.Stderr)
+			assert.Error(t, failed.Err)
+			assert.Equal(t, 3, hook.before)
+		},
+	},
+	{
+		Name: "TestPrune",
+		Fn: func(t *testing.T, ctx context.Context) {
+			fs, err := CreateFilesystem(ctx, "gozfs/fs", CreateFilesystemOptions{})
+			require.NoError(t, err)
+			for _, name := range []string{"auto-1", "auto-2", "manual", "auto-3", "auto-4"} {
+				_, err := fs.Snapshot(ctx, name)
+				require.NoError(t, err)
+			}
+
+			_, err = fs.Prune(ctx, PrunePolicy{Prefix: "auto-"}, false)
+			require.Error(t, err)
+
+			// snapshots are taken within the same second so they must be ordered by creation txg
+			pruned, err := fs.Prune(ctx, PrunePolicy{Prefix: "auto-", KeepLast: 2}, true)
+			require.NoError(t, err)
+			require.Len(t, pruned, 2)
+			assert.Equal(t, "gozfs/fs@auto-2", pruned[0].Info.Name)
+			assert.Equal(t, "gozfs/fs@auto-1", pruned[1].Info.Name)
+			snapshots, err := fs.Snapshots(ctx)
+			require.NoError(t, err)
+			require.Len(t, snapshots, 5)
+
+			pruned, err = fs.Prune(ctx, PrunePolicy{Prefix: "auto-", KeepWithin: time.Hour}, false)
+			require.NoError(t, err)
+			assert.Empty(t, pruned)
+
+			pruned, err = fs.Prune(ctx, PrunePolicy{Prefix: "auto-", KeepLast: 2}, false)
+			require.NoError(t, err)
+			require.Len(t, pruned, 2)
+
+			snapshots, err = fs.Snapshots(ctx)
+			require.NoError(t, err)
+			names := make([]string, 0, len(snapshots))
+			for _, s := range snapshots {
+				names = append(names, s.Info.Name)
+			}
+			sort.Strings(names)
+			assert.Equal(t, []string{"gozfs/fs@auto-3", "gozfs/fs@auto-4", "gozfs/fs@manual"}, names)
+		},
+	},
+	{
+		Name: "TestReplicate",
+		Fn: func(t *testing.T, ctx context.Context) {
+			source, err := CreateFilesystem(ctx, "gozfs/source", CreateFilesystemOptions{})
+			require.NoError(t, err)
+
+			_, err = Replicate(ctx, source, "gozfs/target", ReplicateOptions{})
+			require.Error(t, err)
+
+			require.NoError(t, os.WriteFile("/gozfs/source/content1", []byte("1"), 0o600))
+			_, err = source.Snapshot(ctx, "1")
+			require.NoError(t, err)
+
+			s, err := Replicate(ctx, source, "gozfs/target", ReplicateOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, "gozfs/target@1", s.Info.Name)
+
+			require.NoError(t, os.WriteFile("/gozfs/source/content2", []byte("2"), 0o600))
+			_, err = source.Snapshot(ctx, "2")
+			require.NoError(t, err)
+
+			s, err = Replicate(ctx, source, "gozfs/target", ReplicateOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, "gozfs/target@2", s.Info.Name)
+
+			s, err = Replicate(ctx, source, "gozfs/target", ReplicateOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, "gozfs/target@2", s.Info.Name)
+
+			target, err := GetFilesystem(ctx, "gozfs/target")
+			require.NoError(t, err)
+			snapshots, err := target.Snapshots(ctx)
+			require.NoError(t, err)
+			assert.Len(t, snapshots, 2)
+
+			for _, name := range []string{"3", "4"} {
+				require.NoError(t, os.WriteFile("/gozfs/source/content"+name, []byte(name), 0o600))
+				_, err = source.Snapshot(ctx, name)
+				require.NoError(t, err)
+			}
+			require.NoError(t, os.WriteFile("/gozfs/target/modified", []byte("modified"), 0o600))
+
+			_, err = Replicate(ctx, source, "gozfs/target", ReplicateOptions{})
+			require.Error(t, err)
+
+			s, err = Replicate(ctx, source, "gozfs/target", ReplicateOptions{Force: true})
+			require.NoError(t, err)
+			assert.Equal(t, "gozfs/target@4", s.Info.Name)
+
+			snapshots, err = target.Snapshots(ctx)
+			require.NoError(t, err)
+			names := make([]string, 0, len(snapshots))
+			for _, s := range snapshots {
+				names = append(names, s.Info.Name)
+			}
+			assert.Equal(t, []string{"gozfs/target@1", "gozfs/target@2", "gozfs/target@3", "gozfs/target@4"}, names)
+			_, err = os.Stat("/gozfs/target/modified")
+			assert.True(t, os.IsNotExist(err))
+		},
+	},
+	{
+		Name: "TestEncryptionKeys",
+		Fn: func(t *testing.T, ctx context.Context) {
+			const file = "/gozfs/fs/content"
+			rawKey := []byte("0123456789abcdef0123456789abcdef")
+			const hexKey = "00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff"
+
+			fs, err := CreateFilesystem(ctx, "gozfs/fs", CreateFilesystemOptions{
+				Encryption: &KeyOptions{
+					Encryption: "aes-128-gcm",
+					Format:     KeyFormatRaw,
+					Provider:   StaticKeyProvider{Default: rawKey},
+				},
+			})
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(file, []byte("test"), 0o600))
+
+			encryption, exists, err := fs.GetProperty(ctx, "encryption")
+			require.NoError(t, err)
+			assert.True(t, exists)
+			assert.Equal(t, "aes-128-gcm", encryption)
+
+			status, err := fs.KeyStatus(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, KeyStatusAvailable, status)
+
+			require.NoError(t, fs.ChangeKey(ctx, ChangeKeyOptions{
+				Key: KeyOptions{Format: KeyFormatHex, Key: []byte(hexKey)},
+			}))
+
+			require.NoError(t, fs.Unmount(ctx))
+			require.NoError(t, fs.UnloadKey(ctx))
+			status, err = fs.KeyStatus(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, KeyStatusUnavailable, status)
+
+			assert.Error(t, fs.LoadKey(ctx, string(rawKey)))
+			require.NoError(t, fs.LoadKey(ctx, hexKey))
+			require.NoError(t, fs.Mount(ctx))
+			content, err := os.ReadFile(file)
+			require.NoError(t, err)
+			assert.Equal(t, "test", string(content))
+
+			child, err := CreateFilesystem(ctx, "gozfs/fs/child", CreateFilesystemOptions{
+				Encryption: &KeyOptions{
+					Provider:    StaticKeyProvider{Default: []byte("supersecret")},
+					PBKDF2Iters: 200000,
+				},
+			})
+			require.NoError(t, err)
+
+			root, _, err := child.GetProperty(ctx, "encryptionroot")
+			require.NoError(t, err)
+			assert.Equal(t, "gozfs/fs/child", root)
+
+			require.NoError(t, child.ChangeKey(ctx, ChangeKeyOptions{Inherit: true}))
+			root, _, err = child.GetProperty(ctx, "encryptionroot")
+			require.NoError(t, err)
+			assert.Equal(t, "gozfs/fs", root)
+
+			plain, err := CreateFilesystem(ctx, "gozfs/plain", CreateFilesystemOptions{})
+			require.NoError(t, err)
+			status, err = plain.KeyStatus(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, KeyStatusNone, status)
+		},
+	},
 	{
 		Name: "TestSend",
 		Fn: func(t *testing.T, ctx context.Context) {
@@ -489,6 +952,204 @@ var zfsTests = []testCase{
 			assert.Equal(t, "test", string(content))
 		},
 	},
+	{
+		Name: "TestDiff",
+		Fn: func(t *testing.T, ctx context.Context) {
+			fs, err := CreateFilesystem(ctx, "gozfs/fs", CreateFilesystemOptions{})
+			require.NoError(t, err)
+
+			require.NoError(t, os.WriteFile("/gozfs/fs/modified", []byte("test"), 0o600))
+			require.NoError(t, os.WriteFile("/gozfs/fs/removed", []byte("test"), 0o600))
+			require.NoError(t, os.WriteFile("/gozfs/fs/renamed", []byte("test"), 0o600))
+			s1, err := fs.Snapshot(ctx, "image1")
+			require.NoError(t, err)
+
+			require.NoError(t, os.WriteFile("/gozfs/fs/modified", []byte("test2"), 0o600))
+			require.NoError(t, os.WriteFile("/gozfs/fs/added file", []byte("test"), 0o600))
+			require.NoError(t, os.Remove("/gozfs/fs/removed"))
+			require.NoError(t, os.Rename("/gozfs/fs/renamed", "/gozfs/fs/renamed2"))
+			s2, err := fs.Snapshot(ctx, "image2")
+			require.NoError(t, err)
+
+			changes := map[string]DiffChange{}
+			ch := make(chan DiffChange)
+			require.NoError(t, parallel.Run(ctx, func(ctx context.Context, spawn parallel.SpawnFn) error {
+				spawn("diff", parallel.Continue, func(ctx context.Context) error {
+					return s1.Diff(ctx, s2, ch)
+				})
+				spawn("collect", parallel.Exit, func(ctx context.Context) error {
+					for change := range ch {
+						changes[change.Path] = change
+					}
+					return nil
+				})
+				return nil
+			}))
+
+			assert.Equal(t, DiffModified, changes["/gozfs/fs/modified"].Type)
+			assert.Equal(t, DiffFileRegular, changes["/gozfs/fs/modified"].FileType)
+			assert.False(t, changes["/gozfs/fs/modified"].Time.IsZero())
+			assert.Equal(t, DiffAdded, changes["/gozfs/fs/added file"].Type)
+			assert.Equal(t, DiffRemoved, changes["/gozfs/fs/removed"].Type)
+			assert.Equal(t, DiffRenamed, changes["/gozfs/fs/renamed"].Type)
+			assert.Equal(t, "/gozfs/fs/renamed2", changes["/gozfs/fs/renamed"].NewPath)
+		},
+	},
+	{
+		Name: "TestKeyProvider",
+		Fn: func(t *testing.T, ctx context.Context) {
+			provider := LocalKMS{MasterKey: []byte("master key")}
+
+			fs, err := CreateFilesystem(ctx, "gozfs/fs", CreateFilesystemOptions{
+				Encryption: &KeyOptions{Format: KeyFormatHex, Provider: provider},
+			})
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile("/gozfs/fs/content", []byte("test"), 0o600))
+
+			require.NoError(t, fs.Unmount(ctx))
+			require.NoError(t, fs.UnloadKey(ctx))
+			require.Error(t, fs.Mount(ctx))
+			require.NoError(t, fs.MountWithOptions(ctx, MountOptions{KeyProvider: provider}))
+
+			s, err := fs.Snapshot(ctx, "image")
+			require.NoError(t, err)
+			fsKey, err := provider.Key(ctx, "gozfs/fs")
+			require.NoError(t, err)
+
+			r, w := io.Pipe()
+			require.NoError(t, parallel.Run(ctx, func(ctx context.Context, spawn parallel.SpawnFn) error {
+				spawn("send", parallel.Continue, func(ctx context.Context) error {
+					return s.Send(ctx, SendOptions{Raw: true}, w)
+				})
+				spawn("receive", parallel.Exit, func(ctx context.Context) error {
+					_, err := ReceiveSnapshotWithOptions(ctx, r, "gozfs/copy@received", ReceiveOptions{
+						KeyProvider: StaticKeyProvider{Keys: map[string][]byte{"gozfs/copy": fsKey}},
+					})
+					return err
+				})
+				return nil
+			}))
+
+			fsCopy, err := GetFilesystem(ctx, "gozfs/copy")
+			require.NoError(t, err)
+			status, err := fsCopy.KeyStatus(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, KeyStatusAvailable, status)
+			require.NoError(t, fsCopy.Mount(ctx))
+
+			content, err := os.ReadFile("/gozfs/copy/content")
+			require.NoError(t, err)
+			assert.Equal(t, "test", string(content))
+
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(dir+"/gozfs%2Ffs", []byte("file key"), 0o600))
+			key, err := FileKeyProvider{Dir: dir}.Key(ctx, "gozfs/fs")
+			require.NoError(t, err)
+			assert.Equal(t, "file key", string(key))
+
+			t.Setenv("GOZFS_KEY_GOZFS_FS", "env key")
+			key, err = EnvKeyProvider{Prefix: "GOZFS_KEY_"}.Key(ctx, "gozfs/fs")
+			require.NoError(t, err)
+			assert.Equal(t, "env key", string(key))
+		},
+	},
+	{
+		Name: "TestKeyProviderFileLocation",
+		Fn: func(t *testing.T, ctx context.Context) {
+			const hexKey = "00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff"
+			keyFile := filepath.Join(t.TempDir(), "key")
+			require.NoError(t, os.WriteFile(keyFile, []byte(hexKey), 0o600))
+
+			fs, err := CreateFilesystem(ctx, "gozfs/fs", CreateFilesystemOptions{
+				Encryption: &KeyOptions{Format: KeyFormatHex, Location: "file://" + keyFile},
+			})
+			require.NoError(t, err)
+			require.NoError(t, fs.Unmount(ctx))
+			require.NoError(t, fs.UnloadKey(ctx))
+
+			// key file is gone so the key may be loaded only if it is taken from the provider
+			require.NoError(t, os.Remove(keyFile))
+			require.Error(t, fs.Mount(ctx))
+			require.NoError(t, fs.LoadKeyFromProvider(ctx, StaticKeyProvider{Default: []byte(hexKey)}))
+
+			status, err := fs.KeyStatus(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, KeyStatusAvailable, status)
+			require.NoError(t, fs.Mount(ctx))
+
+			location, _, err := fs.GetProperty(ctx, "keylocation")
+			require.NoError(t, err)
+			assert.Equal(t, "file://"+keyFile, location)
+		},
+	},
+	{
+		Name: "TestLoadKeys",
+		Fn: func(t *testing.T, ctx context.Context) {
+			provider := LocalKMS{MasterKey: []byte("master key")}
+
+			for _, name := range []string{"gozfs/a", "gozfs/b"} {
+				_, err := CreateFilesystem(ctx, name, CreateFilesystemOptions{
+					Encryption: &KeyOptions{Format: KeyFormatHex, Provider: provider},
+				})
+				require.NoError(t, err)
+				require.NoError(t, os.WriteFile("/"+name+"/content", []byte(name), 0o600))
+			}
+			child, err := CreateFilesystem(ctx, "gozfs/a/child", CreateFilesystemOptions{})
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile("/gozfs/a/child/content", []byte("gozfs/a/child"), 0o600))
+
+			for _, name := range []string{"gozfs/a/child", "gozfs/a", "gozfs/b"} {
+				fs, err := GetFilesystem(ctx, name)
+				require.NoError(t, err)
+				require.NoError(t, fs.Unmount(ctx))
+			}
+			for _, name := range []string{"gozfs/a", "gozfs/b"} {
+				fs, err := GetFilesystem(ctx, name)
+				require.NoError(t, err)
+				require.NoError(t, fs.UnloadKey(ctx))
+			}
+
+			results, err := LoadKeys(ctx, "gozfs/a/child", provider, LoadKeysOptions{})
+			require.NoError(t, err)
+			require.Len(t, results, 1)
+			assert.Equal(t, LoadKeyResult{
+				Dataset:        "gozfs/a/child",
+				EncryptionRoot: "gozfs/a",
+				KeyLoaded:      true,
+			}, results[0])
+
+			status, err := child.KeyStatus(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, KeyStatusAvailable, status)
+
+			results, err = LoadKeys(ctx, "gozfs", provider, LoadKeysOptions{Recursive: true, Mount: true})
+			require.NoError(t, err)
+			require.Len(t, results, 4)
+			assert.Equal(t, LoadKeyResult{Dataset: "gozfs"}, results[0])
+			assert.Equal(t, LoadKeyResult{
+				Dataset:        "gozfs/a",
+				EncryptionRoot: "gozfs/a",
+				Mounted:        true,
+			}, results[1])
+			assert.Equal(t, LoadKeyResult{
+				Dataset:        "gozfs/a/child",
+				EncryptionRoot: "gozfs/a",
+				Mounted:        true,
+			}, results[2])
+			assert.Equal(t, LoadKeyResult{
+				Dataset:        "gozfs/b",
+				EncryptionRoot: "gozfs/b",
+				KeyLoaded:      true,
+				Mounted:        true,
+			}, results[3])
+
+			for _, name := range []string{"gozfs/a", "gozfs/a/child", "gozfs/b"} {
+				content, err := os.ReadFile("/" + name + "/content")
+				require.NoError(t, err)
+				assert.Equal(t, name, string(content))
+			}
+		},
+	},
 	{
 		Name: "TestHolds",
 		Fn: func(t *testing.T, ctx context.Context) {
@@ -550,3 +1211,57 @@ func cleanZFS() {
 	_ = exec.Command("zpool", "destroy", "gozfs").Run()
 	_ = exec.Command("rmmod", "brd").Run()
 }
+
+type testSpan struct {
+	name       string
+	attributes map[string]string
+	err        error
+	ended      bool
+}
+
+func (s *testSpan) SetAttribute(key, value string) {
+	s.attributes[key] = value
+}
+
+func (s *testSpan) RecordError(err error) {
+	s.err = err
+}
+
+func (s *testSpan) End() {
+	s.ended = true
+}
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &testSpan{name: name, attributes: map[string]string{}}
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+func TestTraceHook(t *testing.T) {
+	tracer := &testTracer{}
+	hook := &TraceHook{Tracer: tracer}
+
+	info := &CommandInfo{Command: "zfs", Args: []string{"load-key", "tank/fs"}, Stdin: true}
+	ctx := hook.Before(context.Background(), info)
+	info.ExitCode = 1
+	info.Stderr = "Key load error"
+	info.Err = errors.New("exit status 1")
+	hook.After(ctx, info)
+
+	require.Len(t, tracer.spans, 1)
+	span := tracer.spans[0]
+	assert.Equal(t, "zfs load-key", span.name)
+	assert.Equal(t, map[string]string{
+		"command":  "zfs",
+		"args":     "load-key tank/fs",
+		"stdin":    "true",
+		"exitCode": "1",
+		"stderr":   "Key load error",
+	}, span.attributes)
+	assert.Equal(t, info.Err, span.err)
+	assert.True(t, span.ended)
+}
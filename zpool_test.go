@@ -2,10 +2,16 @@ package zfs
 
 import (
 	"context"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/outofforest/logger"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -54,6 +60,356 @@ var zpoolTests = []testCase{
 			assert.Equal(t, "gozpool2", pools[1].Name)
 		},
 	},
+	{
+		Name: "TestCreatePool",
+		Fn: func(t *testing.T, ctx context.Context) {
+			dir := t.TempDir()
+			devices := make([]string, 0, 3)
+			for _, name := range []string{"dev1", "dev2", "dev3"} {
+				device := filepath.Join(dir, name)
+				require.NoError(t, os.WriteFile(device, nil, 0o600))
+				require.NoError(t, os.Truncate(device, 128*1024*1024))
+				devices = append(devices, device)
+			}
+
+			pool, err := CreatePool(ctx, "gozpool3", PoolLayout{
+				Data: []Vdev{Mirror(devices[0], devices[1])},
+				Log:  []Vdev{Stripe(devices[2])},
+			}, CreatePoolOptions{
+				Properties:           map[string]string{"comment": "test"},
+				FilesystemProperties: map[string]string{"compression": "lz4"},
+				Mountpoint:           "/gozpool3root",
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "gozpool3", pool.Name)
+
+			pools, err := Pools(ctx)
+			require.NoError(t, err)
+			require.Len(t, pools, 3)
+
+			fs, err := GetFilesystem(ctx, "gozpool3")
+			require.NoError(t, err)
+			assert.Equal(t, "/gozpool3root", fs.Info.Mountpoint)
+			assert.Equal(t, "lz4", fs.Info.Compression)
+
+			require.NoError(t, pool.Destroy(ctx))
+			_, err = GetPool(ctx, "gozpool3")
+			require.Error(t, err)
+
+			_, err = CreatePool(ctx, "gozpool3", PoolLayout{}, CreatePoolOptions{})
+			require.Error(t, err)
+		},
+	},
+	{
+		Name: "TestStatus",
+		Fn: func(t *testing.T, ctx context.Context) {
+			pool, err := GetPool(ctx, "gozpool1")
+			require.NoError(t, err)
+
+			status, err := pool.Status(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, "gozpool1", status.Name)
+			assert.Equal(t, "ONLINE", status.State)
+			assert.Zero(t, status.ErrorCount)
+			assert.Nil(t, status.Scan)
+			require.NotNil(t, status.Vdevs)
+			assert.Equal(t, "gozpool1", status.Vdevs.Name)
+			assert.Equal(t, "ONLINE", status.Vdevs.State)
+			require.Len(t, status.Vdevs.Children, 1)
+			assert.Equal(t, "/dev/ram0", status.Vdevs.Children[0].Name)
+			assert.Equal(t, "ONLINE", status.Vdevs.Children[0].State)
+		},
+	},
+	{
+		Name: "TestPoolProperties",
+		Fn: func(t *testing.T, ctx context.Context) {
+			pool, err := GetPool(ctx, "gozpool1")
+			require.NoError(t, err)
+
+			props, err := pool.Properties(ctx)
+			require.NoError(t, err)
+			assert.NotZero(t, props.Size)
+			assert.NotZero(t, props.Allocated)
+			assert.NotZero(t, props.Free)
+			assert.NotZero(t, props.GUID)
+			assert.Equal(t, "ONLINE", props.Health)
+			assert.Equal(t, 1.0, props.DedupRatio)
+			assert.False(t, props.ReadOnly)
+			assert.False(t, props.Autotrim)
+			assert.Empty(t, props.Altroot)
+			assert.Empty(t, props.Comment)
+
+			require.NoError(t, pool.SetProperty(ctx, "comment", "test"))
+			require.NoError(t, pool.SetProperty(ctx, "autotrim", "on"))
+
+			comment, exists, err := pool.GetProperty(ctx, "comment")
+			require.NoError(t, err)
+			assert.True(t, exists)
+			assert.Equal(t, "test", comment)
+
+			props, err = pool.Properties(ctx)
+			require.NoError(t, err)
+			assert.True(t, props.Autotrim)
+			assert.Equal(t, "test", props.Comment)
+
+			features, err := pool.Features(ctx)
+			require.NoError(t, err)
+			require.NotEmpty(t, features)
+			for _, f := range features {
+				assert.NotEmpty(t, f.Name)
+				assert.Contains(t, []string{"enabled", "active"}, f.State)
+			}
+		},
+	},
+	{
+		Name: "TestScrubAndTrim",
+		Fn: func(t *testing.T, ctx context.Context) {
+			pool, err := GetPool(ctx, "gozpool1")
+			require.NoError(t, err)
+			_, err = CreateFilesystem(ctx, "gozpool1/fs", CreateFilesystemOptions{})
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile("/gozpool1/fs/content", make([]byte, 10*1024*1024), 0o600))
+
+			require.NoError(t, pool.Scrub(ctx, ScrubStart))
+			require.NoError(t, pool.Wait(ctx, WaitOptions{
+				Activities: []WaitActivity{WaitScrub},
+				Interval:   10 * time.Millisecond,
+				Progress: func(status *PoolStatus) {
+					assert.Equal(t, "gozpool1", status.Name)
+				},
+			}))
+
+			status, err := pool.Status(ctx)
+			require.NoError(t, err)
+			require.NotNil(t, status.Scan)
+			assert.Equal(t, ScanScrub, status.Scan.Function)
+			assert.Equal(t, ScanFinished, status.Scan.State)
+			assert.Zero(t, status.Scan.Errors)
+
+			require.Error(t, pool.Scrub(ctx, ScrubStop))
+
+			require.NoError(t, pool.Trim(ctx, TrimOptions{Rate: 1024 * 1024 * 1024}))
+			require.NoError(t, pool.Wait(ctx, WaitOptions{Activities: []WaitActivity{WaitTrim}}))
+
+			waitCtx, cancel := context.WithCancel(ctx)
+			cancel()
+			require.NoError(t, pool.Scrub(ctx, ScrubStart))
+			require.NoError(t, pool.Scrub(ctx, ScrubPause))
+			require.Error(t, pool.Wait(waitCtx, WaitOptions{Activities: []WaitActivity{WaitScrub}}))
+			require.NoError(t, pool.Scrub(ctx, ScrubStop))
+		},
+	},
+	{
+		Name: "TestIOStat",
+		Fn: func(t *testing.T, ctx context.Context) {
+			pool, err := GetPool(ctx, "gozpool1")
+			require.NoError(t, err)
+
+			statCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			samples := make(chan IOStatSample)
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- pool.IOStat(statCtx, time.Second, IOStatOptions{Verbose: true, Latency: true}, samples)
+			}()
+
+			for i := 0; i < 2; i++ {
+				sample, ok := <-samples
+				require.True(t, ok)
+				assert.Equal(t, "gozpool1", sample.Pool.Name)
+				assert.NotZero(t, sample.Pool.Free)
+				assert.NotNil(t, sample.Pool.Latency)
+				assert.NotEmpty(t, sample.Vdevs)
+			}
+			cancel()
+			for range samples {
+			}
+			require.Error(t, <-errCh)
+
+			require.Error(t, pool.IOStat(ctx, time.Second, IOStatOptions{Histograms: true, Latency: true},
+				make(chan IOStatSample)))
+		},
+	},
+	{
+		Name: "TestHistory",
+		Fn: func(t *testing.T, ctx context.Context) {
+			pool, err := GetPool(ctx, "gozpool1")
+			require.NoError(t, err)
+			_, err = CreateFilesystem(ctx, "gozpool1/fs", CreateFilesystemOptions{})
+			require.NoError(t, err)
+
+			records, err := pool.History(ctx, false, true)
+			require.NoError(t, err)
+			require.NotEmpty(t, records)
+			last := records[len(records)-1]
+			assert.Equal(t, "zfs create gozpool1/fs", last.Command)
+			assert.Equal(t, "root", last.User)
+			assert.NotEmpty(t, last.Host)
+
+			records, err = pool.History(ctx, true, false)
+			require.NoError(t, err)
+			var found bool
+			for _, r := range records {
+				if r.Internal && r.Event == "create" && r.Dataset == "gozpool1/fs" {
+					found = true
+					assert.NotZero(t, r.TXG)
+				}
+			}
+			assert.True(t, found)
+		},
+	},
+	{
+		Name: "TestCheckpoint",
+		Fn: func(t *testing.T, ctx context.Context) {
+			pool, err := GetPool(ctx, "gozpool1")
+			require.NoError(t, err)
+
+			checkpoint, err := pool.CheckpointStatus(ctx)
+			require.NoError(t, err)
+			assert.Nil(t, checkpoint)
+
+			require.NoError(t, pool.Checkpoint(ctx))
+			require.Error(t, pool.Checkpoint(ctx))
+
+			_, err = CreateFilesystem(ctx, "gozpool1/fs", CreateFilesystemOptions{})
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile("/gozpool1/fs/content", make([]byte, 1024*1024), 0o600))
+
+			checkpoint, err = pool.CheckpointStatus(ctx)
+			require.NoError(t, err)
+			require.NotNil(t, checkpoint)
+			assert.Equal(t, CheckpointExists, checkpoint.State)
+			assert.False(t, checkpoint.Created.IsZero())
+
+			require.NoError(t, pool.Export(ctx))
+			pool, err = ImportPoolWithOptions(ctx, "gozpool1", ImportOptions{RewindToCheckpoint: true})
+			require.NoError(t, err)
+
+			_, err = GetFilesystem(ctx, "gozpool1/fs")
+			require.Error(t, err)
+			checkpoint, err = pool.CheckpointStatus(ctx)
+			require.NoError(t, err)
+			assert.Nil(t, checkpoint)
+
+			require.NoError(t, pool.Checkpoint(ctx))
+			require.NoError(t, pool.DiscardCheckpoint(ctx))
+			require.NoError(t, pool.Wait(ctx, WaitOptions{Activities: []WaitActivity{WaitDiscard}}))
+			checkpoint, err = pool.CheckpointStatus(ctx)
+			require.NoError(t, err)
+			assert.Nil(t, checkpoint)
+		},
+	},
+	{
+		Name: "TestVdevManagement",
+		Fn: func(t *testing.T, ctx context.Context) {
+			dir := t.TempDir()
+			devices := make([]string, 0, 4)
+			for _, name := range []string{"dev1", "dev2", "dev3", "dev4"} {
+				device := filepath.Join(dir, name)
+				require.NoError(t, os.WriteFile(device, nil, 0o600))
+				require.NoError(t, os.Truncate(device, 128*1024*1024))
+				devices = append(devices, device)
+			}
+
+			pool, err := CreatePool(ctx, "gozpool3", PoolLayout{Data: []Vdev{Stripe(devices[0])}}, CreatePoolOptions{})
+			require.NoError(t, err)
+
+			status, err := pool.Attach(ctx, devices[0], devices[1], false)
+			require.NoError(t, err)
+			require.Len(t, status.Vdevs.Children, 1)
+			assert.Equal(t, "mirror-0", status.Vdevs.Children[0].Name)
+			require.NoError(t, pool.Wait(ctx, WaitOptions{Activities: []WaitActivity{WaitResilver}}))
+
+			status, err = pool.Offline(ctx, true, devices[1])
+			require.NoError(t, err)
+			assert.Equal(t, "DEGRADED", status.State)
+			assert.Equal(t, "OFFLINE", status.Vdevs.Children[0].Children[1].State)
+
+			status, err = pool.Online(ctx, false, devices[1])
+			require.NoError(t, err)
+			assert.Equal(t, "ONLINE", status.Vdevs.Children[0].Children[1].State)
+
+			status, err = pool.Detach(ctx, devices[1])
+			require.NoError(t, err)
+			require.Len(t, status.Vdevs.Children, 1)
+			assert.Equal(t, devices[0], status.Vdevs.Children[0].Name)
+
+			status, err = pool.Add(ctx, PoolLayout{Data: []Vdev{Stripe(devices[2])}}, false)
+			require.NoError(t, err)
+			require.Len(t, status.Vdevs.Children, 2)
+			assert.Equal(t, devices[2], status.Vdevs.Children[1].Name)
+
+			_, err = pool.Remove(ctx, devices[2])
+			require.NoError(t, err)
+			require.NoError(t, pool.Wait(ctx, WaitOptions{Activities: []WaitActivity{WaitRemove}}))
+			status, err = pool.Status(ctx)
+			require.NoError(t, err)
+			require.NotNil(t, status.Removal)
+			assert.Equal(t, ScanFinished, status.Removal.State)
+
+			_, err = pool.Replace(ctx, devices[0], devices[3], false)
+			require.NoError(t, err)
+			require.NoError(t, pool.Wait(ctx, WaitOptions{Activities: []WaitActivity{WaitReplace}}))
+
+			status, err = pool.Clear(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, "ONLINE", status.State)
+			require.Len(t, status.Vdevs.Children, 1)
+			assert.Equal(t, devices[3], status.Vdevs.Children[0].Name)
+
+			require.NoError(t, pool.Destroy(ctx))
+		},
+	},
+	{
+		Name: "TestImportOptions",
+		Fn: func(t *testing.T, ctx context.Context) {
+			pool, err := GetPool(ctx, "gozpool1")
+			require.NoError(t, err)
+			props, err := pool.Properties(ctx)
+			require.NoError(t, err)
+			_, err = CreateFilesystem(ctx, "gozpool1/fs", CreateFilesystemOptions{})
+			require.NoError(t, err)
+
+			require.NoError(t, pool.ExportWithOptions(ctx, ExportOptions{Force: true}))
+
+			pools, err := ImportablePools(ctx, nil)
+			require.NoError(t, err)
+			require.Len(t, pools, 1)
+			assert.Equal(t, "gozpool1", pools[0].Name)
+			assert.Equal(t, props.GUID, pools[0].GUID)
+			assert.Equal(t, "ONLINE", pools[0].State)
+			require.NotNil(t, pools[0].Vdevs)
+			require.Len(t, pools[0].Vdevs.Children, 1)
+
+			pool, err = ImportPoolWithOptions(ctx, strconv.FormatUint(props.GUID, 10), ImportOptions{
+				NewName:  "gozpool3",
+				ReadOnly: true,
+				NoMount:  true,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "gozpool3", pool.Name)
+
+			props, err = pool.Properties(ctx)
+			require.NoError(t, err)
+			assert.True(t, props.ReadOnly)
+
+			fs, err := GetFilesystem(ctx, "gozpool3/fs")
+			require.NoError(t, err)
+			mounted, err := fs.IsMounted(ctx)
+			require.NoError(t, err)
+			assert.False(t, mounted)
+
+			require.NoError(t, pool.Export(ctx))
+			pool, err = ImportPoolWithOptions(ctx, "gozpool3", ImportOptions{NewName: "gozpool1"})
+			require.NoError(t, err)
+			assert.Equal(t, "gozpool1", pool.Name)
+
+			pools, err = ImportablePools(ctx, []string{t.TempDir()})
+			require.NoError(t, err)
+			assert.Empty(t, pools)
+		},
+	},
 }
 
 func TestZPool(t *testing.T) {
@@ -83,5 +439,393 @@ func TestZPool(t *testing.T) {
 func cleanZPool() {
 	_ = exec.Command("zpool", "destroy", "gozpool1").Run()
 	_ = exec.Command("zpool", "destroy", "gozpool2").Run()
+	_ = exec.Command("zpool", "destroy", "gozpool3").Run()
 	_ = exec.Command("rmmod", "brd").Run()
 }
+
+const statusText = `  pool: tank
+ state: DEGRADED
+status: One or more devices could not be used because the label is missing or
+	invalid.  Sufficient replicas exist for the pool to continue
+	functioning in a degraded state.
+action: Replace the device using 'zpool replace'.
+   see: https://openzfs.github.io/openzfs-docs/msg/ZFS-8000-4J
+  scan: scrub in progress since Sun Oct 18 10:00:00 2026
+	1048576 scanned at 1024/s, 524288 issued at 512/s, 2097152 total
+	0 repaired, 25.00% done, 00:00:03 to go
+config:
+
+	NAME          STATE     READ WRITE CKSUM
+	tank          DEGRADED     0     0     0
+	  mirror-0    DEGRADED     0     0     0
+	    /dev/sda  ONLINE       0     0     2
+	    /dev/sdb  UNAVAIL      3     1     0  was /dev/sdb1
+	logs
+	  /dev/sdc    ONLINE       0     0     0
+	cache
+	  /dev/sdd    ONLINE       0     0     0
+	spares
+	  /dev/sde    AVAIL
+
+errors: 5 data errors, use '-v' for a list
+
+  pool: other
+ state: ONLINE
+  scan: resilvered 1024 in 00:00:01 with 0 errors on Sun Oct 18 11:00:00 2026
+config:
+
+	NAME        STATE     READ WRITE CKSUM
+	other       ONLINE       0     0     0
+	  /dev/sdf  ONLINE       0     0     0
+
+errors: No known data errors
+`
+
+const importText = `   pool: tank
+     id: 15935185229574424532
+  state: ONLINE
+ action: The pool can be imported using its name or numeric identifier.
+ config:
+
+	tank          ONLINE
+	  mirror-0    ONLINE
+	    /tmp/dev1  ONLINE
+	    /tmp/dev2  ONLINE
+`
+
+const statusJSON = `{
+  "output_version": {"command": "zpool status", "vers_major": 0, "vers_minor": 1},
+  "pools": {
+    "tank": {
+      "name": "tank",
+      "state": "ONLINE",
+      "scan_stats": {
+        "function": "SCRUB",
+        "state": "FINISHED",
+        "start_time": 1792317600,
+        "end_time": 1792317660,
+        "to_examine": 2097152,
+        "examined": 2097152,
+        "errors": 0
+      },
+      "vdevs": {
+        "tank": {
+          "name": "tank",
+          "vdev_type": "root",
+          "state": "ONLINE",
+          "read_errors": 0,
+          "write_errors": 0,
+          "checksum_errors": 0,
+          "vdevs": {
+            "mirror-0": {
+              "name": "mirror-0",
+              "vdev_type": "mirror",
+              "state": "ONLINE",
+              "read_errors": 0,
+              "write_errors": 0,
+              "checksum_errors": 0,
+              "vdevs": {
+                "/dev/sdb": {"name": "/dev/sdb", "state": "ONLINE", "read_errors": 0, "write_errors": 0, "checksum_errors": 0},
+                "/dev/sda": {"name": "/dev/sda", "state": "ONLINE", "read_errors": 1, "write_errors": 2, "checksum_errors": 3}
+              }
+            }
+          }
+        }
+      },
+      "logs": {
+        "/dev/sdc": {"name": "/dev/sdc", "state": "ONLINE", "read_errors": "0", "write_errors": "0", "checksum_errors": "0"}
+      },
+      "error_count": "0"
+    }
+  }
+}`
+
+func TestParseStatus(t *testing.T) {
+	statuses, err := parseStatusText([]byte(statusText))
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+
+	status := statuses[0]
+	assert.Equal(t, "tank", status.Name)
+	assert.Equal(t, "DEGRADED", status.State)
+	assert.Equal(t, "One or more devices could not be used because the label is missing or invalid.  "+
+		"Sufficient replicas exist for the pool to continue functioning in a degraded state.", status.Status)
+	assert.Equal(t, "Replace the device using 'zpool replace'.", status.Action)
+	assert.EqualValues(t, 5, status.ErrorCount)
+
+	require.NotNil(t, status.Scan)
+	assert.Equal(t, ScanScrub, status.Scan.Function)
+	assert.Equal(t, ScanScanning, status.Scan.State)
+	assert.Equal(t, time.Date(2026, 10, 18, 10, 0, 0, 0, time.Local), status.Scan.StartTime)
+	assert.Equal(t, 25.0, status.Scan.PercentDone)
+
+	require.NotNil(t, status.Vdevs)
+	assert.Equal(t, &VdevStatus{
+		Name:  "tank",
+		State: "DEGRADED",
+		Children: []*VdevStatus{
+			{
+				Name:  "mirror-0",
+				State: "DEGRADED",
+				Children: []*VdevStatus{
+					{Name: "/dev/sda", State: "ONLINE", ChecksumErrors: 2},
+					{Name: "/dev/sdb", State: "UNAVAIL", ReadErrors: 3, WriteErrors: 1, Message: "was /dev/sdb1"},
+				},
+			},
+		},
+	}, status.Vdevs)
+	assert.Equal(t, []*VdevStatus{{Name: "/dev/sdc", State: "ONLINE"}}, status.Logs)
+	assert.Equal(t, []*VdevStatus{{Name: "/dev/sdd", State: "ONLINE"}}, status.Cache)
+	assert.Equal(t, []*VdevStatus{{Name: "/dev/sde", State: "AVAIL"}}, status.Spares)
+
+	status = statuses[1]
+	assert.Equal(t, "other", status.Name)
+	assert.Zero(t, status.ErrorCount)
+	require.NotNil(t, status.Scan)
+	assert.Equal(t, ScanResilver, status.Scan.Function)
+	assert.Equal(t, ScanFinished, status.Scan.State)
+	assert.Equal(t, time.Date(2026, 10, 18, 11, 0, 0, 0, time.Local), status.Scan.EndTime)
+	require.Len(t, status.Vdevs.Children, 1)
+	assert.Equal(t, "/dev/sdf", status.Vdevs.Children[0].Name)
+
+	statuses, err = parseStatusText([]byte(importText))
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "tank", statuses[0].Name)
+	assert.EqualValues(t, uint64(15935185229574424532), statuses[0].GUID)
+	assert.Equal(t, "ONLINE", statuses[0].State)
+	assert.Equal(t, &VdevStatus{
+		Name:  "tank",
+		State: "ONLINE",
+		Children: []*VdevStatus{
+			{
+				Name:  "mirror-0",
+				State: "ONLINE",
+				Children: []*VdevStatus{
+					{Name: "/tmp/dev1", State: "ONLINE"},
+					{Name: "/tmp/dev2", State: "ONLINE"},
+				},
+			},
+		},
+	}, statuses[0].Vdevs)
+
+	status, err = parseStatusJSON([]byte(statusJSON), "tank")
+	require.NoError(t, err)
+	assert.Equal(t, "tank", status.Name)
+	require.NotNil(t, status.Scan)
+	assert.Equal(t, ScanScrub, status.Scan.Function)
+	assert.Equal(t, ScanFinished, status.Scan.State)
+	assert.Equal(t, time.Unix(1792317660, 0), status.Scan.EndTime)
+	require.Len(t, status.Vdevs.Children, 1)
+	require.Len(t, status.Vdevs.Children[0].Children, 2)
+	assert.Equal(t, "/dev/sdb", status.Vdevs.Children[0].Children[0].Name)
+	assert.Equal(t, &VdevStatus{
+		Name:           "/dev/sda",
+		State:          "ONLINE",
+		ReadErrors:     1,
+		WriteErrors:    2,
+		ChecksumErrors: 3,
+	}, status.Vdevs.Children[0].Children[1])
+	assert.Equal(t, []*VdevStatus{{Name: "/dev/sdc", State: "ONLINE"}}, status.Logs)
+
+	removal := parseRemovalText("Evacuation of /dev/sdb in progress since Sun Oct 18 10:00:00 2026 " +
+		"1048576 copied out of 4194304 at 1024/s, 25.00% done, 0h0m to go")
+	assert.Equal(t, ScanScanning, removal.State)
+	assert.EqualValues(t, 1048576, removal.Copied)
+	assert.EqualValues(t, 4194304, removal.ToCopy)
+	assert.Equal(t, 25.0, removal.PercentDone)
+
+	checkpoint := parseCheckpointText("created Sun Oct 18 10:00:00 2026, consumes 1.50M")
+	assert.Equal(t, CheckpointExists, checkpoint.State)
+	assert.Equal(t, time.Date(2026, 10, 18, 10, 0, 0, 0, time.Local), checkpoint.Created)
+	assert.Equal(t, CheckpointDiscarding, parseCheckpointText("discarding").State)
+
+	assert.True(t, isUnsupportedOption(&cmdError{Err: errors.New("exit status 2"), Stderr: "invalid option 'j'\nusage:"}))
+	assert.True(t, isUnsupportedOption(&cmdError{Err: errors.New("exit status 2"),
+		Stderr: "zpool: unrecognized option '--json-int'"}))
+	assert.False(t, isUnsupportedOption(&cmdError{Err: errors.New("exit status 1"),
+		Stderr: "cannot open 'tank': no such pool"}))
+	assert.False(t, isUnsupportedOption(context.Canceled))
+}
+
+const eventsText = `Oct 18 2026 10:00:00.123456789 sysevent.fs.zfs.scrub_start
+        version = 0x0
+        class = "sysevent.fs.zfs.scrub_start"
+        pool = "tank"
+        pool_guid = 0x7f3c0a1d2e4b5c6d
+        eid = 0x2a
+
+Oct 18 2026 10:00:05.000000000 ereport.fs.zfs.checksum
+        class = "ereport.fs.zfs.checksum"
+        pool = "tank"
+        vdev_path = "/dev/sdb"
+        vdev_guid = 0x10
+        detector = (embedded nvlist)
+                version = 0x0
+                scheme = "zfs"
+        (end detector)
+        eid = 0x2b
+
+`
+
+func TestParseEvents(t *testing.T) {
+	parser := &eventParser{}
+	var events []Event
+	for _, line := range strings.Split(eventsText, "\n") {
+		event, err := parser.parse(line)
+		require.NoError(t, err)
+		if event != nil {
+			events = append(events, *event)
+		}
+	}
+
+	require.Len(t, events, 2)
+	assert.Equal(t, time.Date(2026, 10, 18, 10, 0, 0, 123456789, time.Local), events[0].Time)
+	assert.Equal(t, "sysevent.fs.zfs.scrub_start", events[0].Class)
+	assert.Equal(t, "tank", events[0].Pool)
+	assert.EqualValues(t, 0x7f3c0a1d2e4b5c6d, events[0].PoolGUID)
+	assert.EqualValues(t, 42, events[0].EID)
+
+	assert.Equal(t, "ereport.fs.zfs.checksum", events[1].Class)
+	assert.Equal(t, "/dev/sdb", events[1].VdevPath)
+	assert.EqualValues(t, 16, events[1].VdevGUID)
+	assert.EqualValues(t, 43, events[1].EID)
+	assert.Equal(t, "zfs", events[1].Payload["detector.scheme"])
+	assert.Equal(t, "0x2b", events[1].Payload["eid"])
+}
+
+const ioStatText = "1792317600\n" +
+	"tank\t1048576\t2097152\t10\t20\t4096\t8192\t100\t200\t50\t150\t-\t-\t10\t20\t-\t-\t-\n" +
+	"mirror-0\t1048576\t2097152\t10\t20\t4096\t8192\t100\t200\t50\t150\t-\t-\t10\t20\t-\t-\t-\n" +
+	"/dev/sda\t-\t-\t5\t10\t2048\t4096\t100\t200\t50\t150\t-\t-\t10\t20\t-\t-\t-\n" +
+	"logs\t-\t-\t-\t-\t-\t-\t-\t-\t-\t-\t-\t-\t-\t-\t-\t-\t-\n" +
+	"/dev/sdc\t-\t-\t0\t1\t0\t512\t-\t30\t-\t20\t-\t-\t-\t10\t-\t-\t-\n" +
+	"\n" +
+	"1792317601\n" +
+	"tank\t1048576\t2097152\t0\t0\t0\t0\t-\t-\t-\t-\t-\t-\t-\t-\t-\t-\t-\n"
+
+const ioStatHistogramText = "1792317600\n" +
+	"tank\n" +
+	"1\t0\t0\t0\t0\t0\t0\t0\t0\t0\t0\n" +
+	"1024\t1\t2\t3\t4\t5\t6\t7\t8\t9\t10\n" +
+	"/dev/sda\n" +
+	"1\t0\t0\t0\t0\t0\t0\t0\t0\t0\t0\n" +
+	"\n"
+
+func TestParseIOStat(t *testing.T) {
+	parse := func(options IOStatOptions, text string) []IOStatSample {
+		parser := &ioStatParser{options: options}
+		var samples []IOStatSample
+		for _, line := range strings.Split(text, "\n") {
+			sample, err := parser.parse(strings.Split(line, "\t"))
+			require.NoError(t, err)
+			if sample != nil {
+				samples = append(samples, *sample)
+			}
+		}
+		if sample := parser.flush(); sample != nil {
+			samples = append(samples, *sample)
+		}
+		return samples
+	}
+
+	samples := parse(IOStatOptions{Verbose: true, Latency: true}, ioStatText)
+	require.Len(t, samples, 2)
+	assert.Equal(t, time.Unix(1792317600, 0), samples[0].Time)
+	assert.Equal(t, "tank", samples[0].Pool.Name)
+	assert.EqualValues(t, 1048576, samples[0].Pool.Allocated)
+	assert.EqualValues(t, 2097152, samples[0].Pool.Free)
+	assert.EqualValues(t, 10, samples[0].Pool.ReadOps)
+	assert.EqualValues(t, 8192, samples[0].Pool.WriteBytes)
+	require.NotNil(t, samples[0].Pool.Latency)
+	assert.Equal(t, 100*time.Nanosecond, samples[0].Pool.Latency.TotalRead)
+	assert.Equal(t, 150*time.Nanosecond, samples[0].Pool.Latency.DiskWrite)
+	assert.Equal(t, 20*time.Nanosecond, samples[0].Pool.Latency.AsyncQueueWrite)
+	assert.Nil(t, samples[0].Pool.Queues)
+	require.Len(t, samples[0].Vdevs, 3)
+	assert.Equal(t, "mirror-0", samples[0].Vdevs[0].Name)
+	assert.Equal(t, "/dev/sda", samples[0].Vdevs[1].Name)
+	assert.Equal(t, "/dev/sdc", samples[0].Vdevs[2].Name)
+	assert.EqualValues(t, 512, samples[0].Vdevs[2].WriteBytes)
+	assert.Equal(t, time.Unix(1792317601, 0), samples[1].Time)
+	assert.Empty(t, samples[1].Vdevs)
+
+	samples = parse(IOStatOptions{Histograms: true}, ioStatHistogramText)
+	require.Len(t, samples, 1)
+	assert.Equal(t, "tank", samples[0].Pool.Name)
+	require.Len(t, samples[0].Pool.Histogram, 2)
+	assert.Equal(t, IOHistogramBucket{
+		Latency:         1024 * time.Nanosecond,
+		TotalRead:       1,
+		TotalWrite:      2,
+		DiskRead:        3,
+		DiskWrite:       4,
+		SyncQueueRead:   5,
+		SyncQueueWrite:  6,
+		AsyncQueueRead:  7,
+		AsyncQueueWrite: 8,
+		Scrub:           9,
+		Trim:            10,
+	}, samples[0].Pool.Histogram[1])
+	require.Len(t, samples[0].Vdevs, 1)
+	assert.Len(t, samples[0].Vdevs[0].Histogram, 1)
+
+	stat, err := parseIOStat(strings.Split("tank\t1\t2\t3\t4\t5\t6\t"+
+		"0\t0\t0\t0\t0\t0\t0\t0\t0\t0\t0\t"+
+		"1\t2\t3\t4\t5\t6\t7\t8\t9\t10\t11\t12\t13\t14", "\t"))
+	require.NoError(t, err)
+	require.NotNil(t, stat.Latency)
+	require.NotNil(t, stat.Queues)
+	assert.Equal(t, IOQueue{Pending: 1, Active: 2}, stat.Queues.SyncRead)
+	assert.Equal(t, IOQueue{Pending: 13, Active: 14}, stat.Queues.RebuildWrite)
+}
+
+const historyText = `History for 'tank':
+2026-10-18.10:00:00 zpool create tank /dev/sda [user 0 (root) on host1]
+2026-10-18.10:00:01 [txg:5] create tank/fs (130)  [user 0 (root) on host1]
+2026-10-18.10:00:01 [txg:5] set tank/fs (130) compression=15 [user 0 (root) on host1]
+2026-10-18.10:00:02 ioctl snapshot [user 1000 on host1:zone1]
+    input:
+        snaps:
+            tank/fs@snap
+2026-10-18.10:00:03 [internal destroy txg:7] dataset = 140
+2026-10-18.10:00:04 zfs destroy tank/fs@snap
+
+`
+
+func TestParseHistory(t *testing.T) {
+	records, err := parseHistory(historyText)
+	require.NoError(t, err)
+	require.Len(t, records, 6)
+
+	assert.Equal(t, HistoryRecord{
+		Time:    time.Date(2026, 10, 18, 10, 0, 0, 0, time.Local),
+		Command: "zpool create tank /dev/sda",
+		User:    "root",
+		Host:    "host1",
+	}, records[0])
+	assert.Equal(t, HistoryRecord{
+		Time:      time.Date(2026, 10, 18, 10, 0, 1, 0, time.Local),
+		Internal:  true,
+		Event:     "create",
+		TXG:       5,
+		Dataset:   "tank/fs",
+		DatasetID: 130,
+		User:      "root",
+		Host:      "host1",
+	}, records[1])
+	assert.Equal(t, "compression=15", records[2].Message)
+	assert.Equal(t, HistoryRecord{
+		Time:     time.Date(2026, 10, 18, 10, 0, 2, 0, time.Local),
+		Internal: true,
+		Event:    "snapshot",
+		UID:      1000,
+		Host:     "host1",
+		Zone:     "zone1",
+	}, records[3])
+	assert.Equal(t, "destroy", records[4].Event)
+	assert.EqualValues(t, 7, records[4].TXG)
+	assert.Equal(t, "dataset = 140", records[4].Message)
+	assert.Equal(t, "zfs destroy tank/fs@snap", records[5].Command)
+	assert.False(t, records[5].Internal)
+}